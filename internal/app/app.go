@@ -18,6 +18,7 @@ func Run(ctx context.Context, args []string) error {
 	configPath := global.String("config", "", "Path to wemod launcher TOML config")
 	nonInteractive := global.Bool("non-interactive", false, "Disable prompts and require explicit flags")
 	logLevel := global.String("log-level", "", "Override log level (debug|info|warn|error)")
+	lockWait := global.Int("lock-wait", -1, "Seconds to wait for a prefix used by another launcher process")
 	showVersion := global.Bool("version", false, "Print version")
 	global.SetOutput(os.Stderr)
 
@@ -49,10 +50,18 @@ func Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("invalid config general.log_level in %s: %w", cfgPath, err)
 	}
 	cfg.General.LogLevel = configLevel
+	configFormat, err := logging.NormalizeFormat(cfg.General.LogFormat)
+	if err != nil {
+		return fmt.Errorf("invalid config general.log_format in %s: %w", cfgPath, err)
+	}
+	cfg.General.LogFormat = configFormat
 
 	if *nonInteractive {
 		cfg.General.Interactive = false
 	}
+	if *lockWait >= 0 {
+		cfg.General.LockWaitSeconds = *lockWait
+	}
 
 	levelSource := "config"
 	if strings.TrimSpace(*logLevel) != "" {
@@ -70,6 +79,10 @@ func Run(ctx context.Context, args []string) error {
 	}
 	defer logger.Close()
 	logger = logger.WithComponent("app")
+	// Hooks, WeMod and the game inherit the session ID for log correlation.
+	if err := os.Setenv(logging.SessionIDEnv, logger.SessionID()); err != nil {
+		logger.Warn("failed exporting %s: %v", logging.SessionIDEnv, err)
+	}
 
 	logger.StartupBanner(fmt.Sprintf("wemod-launcher %s session start", config.AppVersion))
 	logger.Info("wemod-launcher %s started", config.AppVersion)
@@ -111,13 +124,13 @@ func splitGlobalAndCommandArgs(args []string) ([]string, []string, error) {
 		switch {
 		case arg == "--non-interactive" || arg == "--version":
 			globalArgs = append(globalArgs, arg)
-		case arg == "--config" || arg == "--log-level":
+		case arg == "--config" || arg == "--log-level" || arg == "--lock-wait":
 			if i+1 >= len(args) {
 				return nil, nil, fmt.Errorf("missing value for %s", arg)
 			}
 			globalArgs = append(globalArgs, arg, args[i+1])
 			i++
-		case strings.HasPrefix(arg, "--config=") || strings.HasPrefix(arg, "--log-level="):
+		case strings.HasPrefix(arg, "--config=") || strings.HasPrefix(arg, "--log-level=") || strings.HasPrefix(arg, "--lock-wait="):
 			globalArgs = append(globalArgs, arg)
 		default:
 			commandArgs = append(commandArgs, arg)
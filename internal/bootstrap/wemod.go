@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
 )
@@ -82,10 +84,55 @@ func EnsureWeMod(ctx context.Context, cfg *config.Config, logger *logging.Logger
 		return fmt.Errorf("wemod extracted but executable missing at %s", cfg.Paths.WeModExePath)
 	}
 
+	if product, version, err := readNuspecVersion(installerPath); err != nil {
+		logger.Warn("could not read WeMod version from installer: %v", err)
+	} else {
+		logger.Info("installed %s %s", product, version)
+		if err := compat.WriteInstalledVersion(installRoot, product, version); err != nil {
+			logger.Warn("failed recording WeMod version: %v", err)
+		}
+	}
+
 	logger.Info("WeMod installed to %s", installRoot)
 	return nil
 }
 
+// readNuspecVersion returns the package id and version from the .nuspec
+// manifest at the root of the installer nupkg.
+func readNuspecVersion(installerPath string) (string, string, error) {
+	r, err := zip.OpenReader(installerPath)
+	if err != nil {
+		return "", "", fmt.Errorf("open installer archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, file := range r.File {
+		if strings.Contains(file.Name, "/") || !strings.HasSuffix(strings.ToLower(file.Name), ".nuspec") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return "", "", fmt.Errorf("open nuspec: %w", err)
+		}
+		var manifest struct {
+			Metadata struct {
+				ID      string `xml:"id"`
+				Version string `xml:"version"`
+			} `xml:"metadata"`
+		}
+		err = xml.NewDecoder(rc).Decode(&manifest)
+		rc.Close()
+		if err != nil {
+			return "", "", fmt.Errorf("decode nuspec: %w", err)
+		}
+		if manifest.Metadata.Version == "" {
+			return "", "", errors.New("nuspec has no version")
+		}
+		return manifest.Metadata.ID, manifest.Metadata.Version, nil
+	}
+	return "", "", errors.New("installer archive has no nuspec")
+}
+
 func fetchWeModDownloadURL(ctx context.Context) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scoopMetadataURL, nil)
 	if err != nil {
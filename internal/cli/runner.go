@@ -6,11 +6,15 @@ import (
 	"fmt"
 
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/bootstrap"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/control"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/doctor"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/launch"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/prefix"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/report"
 )
 
 var ErrUsage = errors.New("usage")
@@ -50,7 +54,7 @@ func (r *Runner) Run(ctx context.Context, cfg *config.Config, args []string) err
 		err = launch.Sync(ctx, cfg, r.logger, args[1:])
 	case "reset":
 		r.logger.Debug("dispatch to launch.ResetOwnPrefix")
-		err = launch.ResetOwnPrefix(cfg, r.logger)
+		err = launch.ResetOwnPrefix(ctx, cfg, r.logger)
 	case "prefix":
 		if len(args) < 2 {
 			printPrefixUsage()
@@ -63,10 +67,37 @@ func (r *Runner) Run(ctx context.Context, cfg *config.Config, args []string) err
 		case "build":
 			r.logger.Debug("dispatch to prefix.Build")
 			err = prefix.Build(ctx, cfg, r.logger)
+		case "check":
+			if len(args) < 3 {
+				printPrefixUsage()
+				return ErrUsage
+			}
+			r.logger.Debug("dispatch to launch.CheckPrefix")
+			err = launch.CheckPrefix(cfg, r.logger, args[2:])
 		default:
 			printPrefixUsage()
 			return ErrUsage
 		}
+	case "compat":
+		if len(args) < 2 || args[1] != "list" {
+			printCompatUsage()
+			return ErrUsage
+		}
+		r.logger.Debug("dispatch to compat.List")
+		err = compat.List(cfg, r.logger, args[2:])
+	case "status":
+		r.logger.Debug("dispatch to registry.Status")
+		err = registry.Status(r.logger, args[1:])
+	case "ctl":
+		if len(args) < 2 {
+			printCtlUsage()
+			return ErrUsage
+		}
+		r.logger.Debug("dispatch to control.Ctl")
+		err = control.Ctl(r.logger, args[1:])
+	case "report":
+		r.logger.Debug("dispatch to report.Run")
+		err = report.Run(ctx, cfg, r.logger, args[1:])
 	case "config":
 		if len(args) < 2 || args[1] != "init" {
 			printConfigUsage()
@@ -92,23 +123,37 @@ func (r *Runner) Run(ctx context.Context, cfg *config.Config, args []string) err
 
 func printMainUsage() {
 	fmt.Println("wemod-launcher commands:")
-	fmt.Println("  launch [--] <game command...>")
+	fmt.Println("  launch [--lutris <slug>|--heroic <appName>|--bottles <name>] [--] <game command...>")
+	fmt.Println("  launch [--game <id>] [--prefix <dir>] [--wine <bin>] [--] <game command...>")
 	fmt.Println("  setup")
 	fmt.Println("  doctor")
-	fmt.Println("  sync [--] <proton game command...>")
+	fmt.Println("  sync [--lutris <slug>|--heroic <appName>|--bottles <name>] [--] <proton game command...>")
 	fmt.Println("  reset")
-	fmt.Println("  prefix <download|build>")
+	fmt.Println("  prefix <download|build|check <appid|prefix dir>>")
+	fmt.Println("  compat list [--all]")
+	fmt.Println("  status [--json]")
+	fmt.Println("  ctl [--pid <pid>] <status|restart-wemod|stop-wemod|sync-now>")
+	fmt.Println("  report [--sessions <n>] [--output <file.tar.gz>]")
 	fmt.Println("  config init")
 	fmt.Println("")
 	fmt.Println("global options:")
 	fmt.Println("  --config <path>")
 	fmt.Println("  --log-level <debug|info|warn|error>")
 	fmt.Println("  --non-interactive")
+	fmt.Println("  --lock-wait <seconds>")
 	fmt.Println("  --version")
 }
 
 func printPrefixUsage() {
-	fmt.Println("usage: wemod-launcher prefix <download|build>")
+	fmt.Println("usage: wemod-launcher prefix <download|build|check <appid|prefix dir>>")
+}
+
+func printCompatUsage() {
+	fmt.Println("usage: wemod-launcher compat list [--all]")
+}
+
+func printCtlUsage() {
+	fmt.Println("usage: wemod-launcher ctl [--pid <pid>] <status|restart-wemod|stop-wemod|sync-now>")
 }
 
 func printConfigUsage() {
@@ -0,0 +1,271 @@
+// Package compat records WeMod startup outcomes per WeMod and Proton version
+// and flags combinations known not to work.
+package compat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Startup outcomes, matching the classification in docs/wand-findings.md.
+const (
+	// OutcomeStarted means a renderer process appeared and stayed up.
+	OutcomeStarted = "STARTED"
+	// OutcomeBlackPattern means main, GPU and utility processes are running
+	// but no renderer appeared (the Wand 12.x black window).
+	OutcomeBlackPattern = "BLACK_PATTERN"
+	// OutcomeTimeout means no stable renderer within the observation window.
+	OutcomeTimeout = "TIMEOUT"
+	// OutcomeExited means WeMod exited during the observation window.
+	OutcomeExited = "EXITED"
+)
+
+// Modes for handling known-bad combinations.
+const (
+	ModeWarn   = "warn"
+	ModeRefuse = "refuse"
+	ModeOff    = "off"
+)
+
+// maxResults bounds the size of the results database.
+const maxResults = 500
+
+// Result is one observed WeMod startup. Profile names the WeMod
+// argument/env profile used for the start.
+type Result struct {
+	Time          time.Time `json:"time"`
+	Product       string    `json:"product"`
+	WeModVersion  string    `json:"wemod_version"`
+	ProtonVersion string    `json:"proton_version"`
+	ProtonFlavor  string    `json:"proton_flavor,omitempty"`
+	GameID        string    `json:"game_id,omitempty"`
+	Profile       string    `json:"profile,omitempty"`
+	Outcome       string    `json:"outcome"`
+	Detail        string    `json:"detail,omitempty"`
+}
+
+// Rule marks WeMod versions (and optionally Proton versions) as not working.
+// Versions match by prefix, so "12." covers every 12.x release.
+type Rule struct {
+	Product       string
+	WeModVersion  string
+	ProtonVersion string
+	Reason        string
+}
+
+// KnownBad lists combinations known not to start.
+var KnownBad = []Rule{
+	{
+		Product:      "Wand",
+		WeModVersion: "12.",
+		Reason:       "Wand 12.x never starts its renderer under Wine/Proton (BLACK_PATTERN, see docs/wand-findings.md)",
+	},
+}
+
+// Verdict is the result of checking a combination before launch.
+type Verdict struct {
+	Bad    bool
+	Reason string
+	// Source is "builtin" or "local".
+	Source string
+}
+
+// DB is the local results database, a JSON file of recent results.
+type DB struct {
+	path    string
+	Results []Result `json:"results"`
+}
+
+// DefaultPath returns the results database location inside workDir.
+func DefaultPath(workDir string) string {
+	return filepath.Join(workDir, "compat-results.json")
+}
+
+// Load reads the database at path. A missing file yields an empty database.
+func Load(path string) (*DB, error) {
+	db := &DB{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read compat results: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return db, nil
+	}
+	if err := json.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("decode compat results %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Record appends a result to the database at path and saves it. The update
+// holds a flock on path+".lock" so results of concurrent launcher processes
+// are kept.
+func Record(path string, result Result) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+	db.Results = append(db.Results, result)
+	if len(db.Results) > maxResults {
+		db.Results = db.Results[len(db.Results)-maxResults:]
+	}
+	return db.Save()
+}
+
+// lockFile takes an exclusive flock on path and returns the unlock function.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create compat results dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open compat results lock: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock compat results: %w", err)
+	}
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+	}, nil
+}
+
+// Save writes the database atomically.
+func (db *DB) Save() error {
+	if err := os.MkdirAll(filepath.Dir(db.path), 0o755); err != nil {
+		return fmt.Errorf("create compat results dir: %w", err)
+	}
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode compat results: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), ".compat-results-*.json")
+	if err != nil {
+		return fmt.Errorf("create compat results temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write compat results: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close compat results: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), db.path); err != nil {
+		return fmt.Errorf("replace compat results: %w", err)
+	}
+	return nil
+}
+
+// Summary aggregates the results of one WeMod/Proton combination.
+type Summary struct {
+	Product       string
+	WeModVersion  string
+	ProtonVersion string
+	Outcomes      map[string]int
+	Last          Result
+}
+
+// Summaries groups results by combination, most recently seen first.
+func (db *DB) Summaries() []Summary {
+	index := map[string]int{}
+	var summaries []Summary
+	for _, result := range db.Results {
+		key := comboKey(result.Product, result.WeModVersion, result.ProtonVersion)
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{
+				Product:       result.Product,
+				WeModVersion:  result.WeModVersion,
+				ProtonVersion: result.ProtonVersion,
+				Outcomes:      map[string]int{},
+			})
+		}
+		summaries[i].Outcomes[result.Outcome]++
+		if !result.Time.Before(summaries[i].Last.Time) {
+			summaries[i].Last = result
+		}
+	}
+	sort.SliceStable(summaries, func(a, b int) bool {
+		return summaries[a].Last.Time.After(summaries[b].Last.Time)
+	})
+	return summaries
+}
+
+// Check reports whether a combination is known not to work. A local STARTED
+// result overrides the built-in list; local failures count when the most
+// recent results of the exact combination all failed.
+func (db *DB) Check(product, wemodVersion, protonVersion string) Verdict {
+	var recent []Result
+	if db != nil {
+		key := comboKey(product, wemodVersion, protonVersion)
+		for _, result := range db.Results {
+			if comboKey(result.Product, result.WeModVersion, result.ProtonVersion) == key {
+				recent = append(recent, result)
+			}
+		}
+	}
+	if len(recent) > 3 {
+		recent = recent[len(recent)-3:]
+	}
+
+	failures := 0
+	for _, result := range recent {
+		if result.Outcome == OutcomeStarted {
+			return Verdict{}
+		}
+		if result.Outcome == OutcomeBlackPattern {
+			failures++
+		}
+	}
+
+	for _, rule := range KnownBad {
+		if rule.matches(product, wemodVersion, protonVersion) {
+			return Verdict{Bad: true, Reason: rule.Reason, Source: "builtin"}
+		}
+	}
+	if failures >= 2 && failures == len(recent) {
+		return Verdict{
+			Bad:    true,
+			Reason: fmt.Sprintf("last %d launches of %s %s with %s ended in %s", failures, product, wemodVersion, protonVersion, OutcomeBlackPattern),
+			Source: "local",
+		}
+	}
+	return Verdict{}
+}
+
+func (r Rule) matches(product, wemodVersion, protonVersion string) bool {
+	if r.Product != "" && !strings.EqualFold(r.Product, product) {
+		return false
+	}
+	if wemodVersion == "" || !strings.HasPrefix(wemodVersion, r.WeModVersion) {
+		return false
+	}
+	return r.ProtonVersion == "" || strings.HasPrefix(strings.ToLower(protonVersion), strings.ToLower(r.ProtonVersion))
+}
+
+func comboKey(product, wemodVersion, protonVersion string) string {
+	return strings.ToLower(product) + "\x00" + wemodVersion + "\x00" + protonVersion
+}
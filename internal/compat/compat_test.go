@@ -0,0 +1,149 @@
+package compat
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheck_BuiltinKnownBad(t *testing.T) {
+	db := &DB{}
+	if verdict := db.Check("Wand", "12.12.1", "GE-Proton9-20"); !verdict.Bad || verdict.Source != "builtin" {
+		t.Fatalf("expected builtin known-bad verdict, got %+v", verdict)
+	}
+	if verdict := db.Check("WeMod", "11.6.0", "GE-Proton9-20"); verdict.Bad {
+		t.Fatalf("unexpected verdict for WeMod 11.6.0: %+v", verdict)
+	}
+	var missing *DB
+	if verdict := missing.Check("Wand", "12.0.3", "proton-9.0-3"); !verdict.Bad {
+		t.Fatal("expected builtin rule without a database")
+	}
+}
+
+func TestCheck_LocalResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compat-results.json")
+	for i := 0; i < 2; i++ {
+		if err := Record(path, Result{Product: "WeMod", WeModVersion: "11.7.0", ProtonVersion: "proton-9.0-3", Outcome: OutcomeBlackPattern}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := Record(path, Result{Product: "Wand", WeModVersion: "12.0.3", ProtonVersion: "GE-Proton10-1", Outcome: OutcomeStarted}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(db.Results) != 3 {
+		t.Fatalf("unexpected result count: %d", len(db.Results))
+	}
+	if verdict := db.Check("WeMod", "11.7.0", "proton-9.0-3"); !verdict.Bad || verdict.Source != "local" {
+		t.Fatalf("expected local known-bad verdict, got %+v", verdict)
+	}
+	if verdict := db.Check("WeMod", "11.7.0", "GE-Proton9-20"); verdict.Bad {
+		t.Fatalf("other Proton versions must not be affected: %+v", verdict)
+	}
+	if verdict := db.Check("Wand", "12.0.3", "GE-Proton10-1"); verdict.Bad {
+		t.Fatalf("a local STARTED result should override the builtin list: %+v", verdict)
+	}
+
+	summaries := db.Summaries()
+	if len(summaries) != 2 || summaries[0].Product != "Wand" {
+		t.Fatalf("unexpected summaries: %+v", summaries)
+	}
+	if summaries[1].Outcomes[OutcomeBlackPattern] != 2 {
+		t.Fatalf("unexpected outcome counts: %v", summaries[1].Outcomes)
+	}
+}
+
+func TestRecord_BoundsDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compat-results.json")
+	db := &DB{path: path}
+	for i := 0; i < maxResults; i++ {
+		db.Results = append(db.Results, Result{Time: time.Unix(int64(i), 0), Outcome: OutcomeStarted})
+	}
+	if err := db.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := Record(path, Result{Outcome: OutcomeTimeout}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(db.Results) != maxResults || db.Results[len(db.Results)-1].Outcome != OutcomeTimeout {
+		t.Fatalf("unexpected results after trim: %d", len(db.Results))
+	}
+}
+
+func TestRecord_KeepsConcurrentResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compat-results.json")
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Record(path, Result{GameID: strconv.Itoa(i), Outcome: OutcomeStarted})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(db.Results) != writers {
+		t.Fatalf("expected %d results, got %d", writers, len(db.Results))
+	}
+}
+
+func writeTestAsar(t *testing.T, path, packageJSON string) {
+	t.Helper()
+	index := `{"files":{"package.json":{"size":` + strconv.Itoa(len(packageJSON)) + `,"offset":"0"}}}`
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header[0:4], 4)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(8+len(index)))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(4+len(index)))
+	binary.LittleEndian.PutUint32(header[12:16], uint32(len(index)))
+	data := append(header, index...)
+	data = append(data, packageJSON...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write asar: %v", err)
+	}
+}
+
+func TestDetectWeModVersion(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "WeMod.exe")
+	if product, version := DetectWeModVersion(exe); product != "" || version != "" {
+		t.Fatalf("expected no version, got %q %q", product, version)
+	}
+
+	writeTestAsar(t, filepath.Join(dir, "resources", "app.asar"), `{"name":"wand","productName":"Wand","version":"12.12.1"}`)
+	if product, version := DetectWeModVersion(exe); product != "Wand" || version != "12.12.1" {
+		t.Fatalf("unexpected asar version: %q %q", product, version)
+	}
+
+	if err := WriteInstalledVersion(dir, "WeMod", "11.6.0"); err != nil {
+		t.Fatalf("write version: %v", err)
+	}
+	if product, version := DetectWeModVersion(exe); product != "WeMod" || version != "11.6.0" {
+		t.Fatalf("version file should take precedence, got %q %q", product, version)
+	}
+}
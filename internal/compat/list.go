@@ -0,0 +1,86 @@
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+)
+
+// List prints the recorded startup results grouped by WeMod/Proton
+// combination, followed by the built-in known-bad rules. With --all every
+// single result is printed instead.
+func List(cfg *config.Config, logger *logging.Logger, args []string) error {
+	logger = logger.WithComponent("compat.list")
+	all := false
+	for _, arg := range args {
+		if arg != "--all" {
+			return errors.New("usage: wemod-launcher compat list [--all]")
+		}
+		all = true
+	}
+
+	path := DefaultPath(cfg.Paths.WorkDir)
+	db, err := Load(path)
+	if err != nil {
+		logger.Error("failed loading compat results: %v", err)
+		return err
+	}
+	logger.Debug("loaded %d compat results from %s", len(db.Results), path)
+
+	fmt.Printf("Results: %s (mode=%s)\n", path, cfg.CompatMode())
+	if len(db.Results) == 0 {
+		fmt.Println("  (no launches recorded yet)")
+	}
+	if all {
+		for _, result := range db.Results {
+			fmt.Printf("  %s  %-6s %-10s %-22s %-14s %s\n",
+				result.Time.Local().Format("2006-01-02 15:04"), result.Product, result.WeModVersion,
+				result.ProtonVersion, result.Outcome, valueOrDash(result.GameID))
+		}
+	} else {
+		for _, summary := range db.Summaries() {
+			verdict := db.Check(summary.Product, summary.WeModVersion, summary.ProtonVersion)
+			status := "ok"
+			if verdict.Bad {
+				status = "known-bad (" + verdict.Source + ")"
+			}
+			fmt.Printf("  %-6s %-10s %-22s %-40s last %s  %s\n",
+				summary.Product, summary.WeModVersion, summary.ProtonVersion, formatOutcomes(summary.Outcomes),
+				summary.Last.Time.Local().Format("2006-01-02 15:04"), status)
+		}
+	}
+
+	fmt.Println("Built-in known-bad combinations:")
+	for _, rule := range KnownBad {
+		proton := rule.ProtonVersion
+		if proton == "" {
+			proton = "any Proton"
+		}
+		fmt.Printf("  %s %sx with %s: %s\n", rule.Product, rule.WeModVersion, proton, rule.Reason)
+	}
+	return nil
+}
+
+func formatOutcomes(outcomes map[string]int) string {
+	names := make([]string, 0, len(outcomes))
+	for name := range outcomes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, outcomes[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func valueOrDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package compat
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VersionFile is written next to WeMod.exe by setup and records the
+// installed product and version, e.g. "WeMod 11.6.0".
+const VersionFile = ".wemod_version"
+
+// WriteInstalledVersion records the installed WeMod product and version in
+// installDir.
+func WriteInstalledVersion(installDir, product, version string) error {
+	content := strings.TrimSpace(product + " " + version)
+	if err := os.WriteFile(filepath.Join(installDir, VersionFile), []byte(content+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write wemod version file: %w", err)
+	}
+	return nil
+}
+
+// DetectWeModVersion returns the product name (WeMod or Wand) and version of
+// the installation containing exePath. It reads the version file written by
+// setup and falls back to package.json inside resources/app.asar.
+func DetectWeModVersion(exePath string) (string, string) {
+	dir := filepath.Dir(exePath)
+	if data, err := os.ReadFile(filepath.Join(dir, VersionFile)); err == nil {
+		fields := strings.Fields(string(data))
+		switch len(fields) {
+		case 1:
+			return productForVersion("", fields[0]), fields[0]
+		case 2:
+			return fields[0], fields[1]
+		}
+	}
+
+	manifest, err := readAsarPackageJSON(filepath.Join(dir, "resources", "app.asar"))
+	if err != nil || manifest.Version == "" {
+		return "", ""
+	}
+	return productForVersion(manifest.ProductName, manifest.Version), manifest.Version
+}
+
+// productForVersion names the product. WeMod was renamed to Wand with 12.0.
+func productForVersion(name, version string) string {
+	switch strings.ToLower(name) {
+	case "wemod":
+		return "WeMod"
+	case "wand":
+		return "Wand"
+	}
+	major, _, _ := strings.Cut(version, ".")
+	if n, err := strconv.Atoi(major); err == nil && n >= 12 {
+		return "Wand"
+	}
+	return "WeMod"
+}
+
+type packageManifest struct {
+	ProductName string `json:"productName"`
+	Version     string `json:"version"`
+}
+
+type asarEntry struct {
+	Files  map[string]asarEntry `json:"files"`
+	Offset string               `json:"offset"`
+	Size   int64                `json:"size"`
+}
+
+// readAsarPackageJSON reads package.json from an Electron asar archive. The
+// archive starts with a Chromium pickle holding the JSON file index; file
+// offsets are relative to the end of that header.
+func readAsarPackageJSON(path string) (packageManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return packageManifest{}, err
+	}
+	defer f.Close()
+
+	var prefix [16]byte
+	if _, err := io.ReadFull(f, prefix[:]); err != nil {
+		return packageManifest{}, fmt.Errorf("read asar header: %w", err)
+	}
+	headerSize := binary.LittleEndian.Uint32(prefix[4:8])
+	jsonSize := binary.LittleEndian.Uint32(prefix[12:16])
+	if jsonSize > headerSize || headerSize > 64<<20 {
+		return packageManifest{}, errors.New("invalid asar header")
+	}
+	header := make([]byte, jsonSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return packageManifest{}, fmt.Errorf("read asar index: %w", err)
+	}
+	var root asarEntry
+	if err := json.Unmarshal(header, &root); err != nil {
+		return packageManifest{}, fmt.Errorf("decode asar index: %w", err)
+	}
+	entry, ok := root.Files["package.json"]
+	if !ok || entry.Size <= 0 || entry.Size > 1<<20 {
+		return packageManifest{}, errors.New("asar archive has no package.json")
+	}
+	var offset int64
+	if _, err := fmt.Sscan(entry.Offset, &offset); err != nil {
+		return packageManifest{}, fmt.Errorf("invalid asar offset %q", entry.Offset)
+	}
+
+	data := make([]byte, entry.Size)
+	if _, err := f.ReadAt(data, 8+int64(headerSize)+offset); err != nil {
+		return packageManifest{}, fmt.Errorf("read asar package.json: %w", err)
+	}
+	var manifest packageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return packageManifest{}, fmt.Errorf("decode asar package.json: %w", err)
+	}
+	return manifest, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	toml "github.com/pelletier/go-toml/v2"
 )
@@ -16,15 +18,37 @@ type Config struct {
 	Meta struct {
 		ConfigPath string `toml:"-"`
 	} `toml:"-"`
-	General GeneralConfig `toml:"general"`
-	Paths   PathsConfig   `toml:"paths"`
-	Prefix  PrefixConfig  `toml:"prefix"`
+	General  GeneralConfig  `toml:"general"`
+	Paths    PathsConfig    `toml:"paths"`
+	Prefix   PrefixConfig   `toml:"prefix"`
+	Runtime  RuntimeConfig  `toml:"runtime"`
+	Compat   CompatConfig   `toml:"compat"`
+	WeMod    WeModConfig    `toml:"wemod"`
+	Hooks    HooksConfig    `toml:"hooks"`
+	Shutdown ShutdownConfig `toml:"shutdown"`
+	Redact   RedactConfig   `toml:"redact"`
+	// Games holds per-game overrides keyed by Steam AppID (or the game
+	// identifier resolved by the launcher).
+	Games map[string]GameConfig `toml:"games,omitempty"`
 }
 
 type GeneralConfig struct {
 	Interactive bool   `toml:"interactive"`
 	LogLevel    string `toml:"log_level"`
 	LogFile     string `toml:"log_file"`
+	// LogFormat is the format of the log file, "text" or "json" (one JSON
+	// object per line). Console output is always text.
+	LogFormat string `toml:"log_format"`
+	// LogMaxSizeMB rotates the log file once it reaches this size; 0
+	// disables rotation. LogKeep rotated files are kept, rotated files older
+	// than LogMaxAgeDays (0: no limit) are deleted.
+	LogMaxSizeMB  int  `toml:"log_max_size_mb"`
+	LogKeep       int  `toml:"log_keep"`
+	LogMaxAgeDays int  `toml:"log_max_age_days"`
+	LogCompress   bool `toml:"log_compress"`
+	// LockWaitSeconds is how long to wait for a prefix used by another
+	// launcher process; 0 fails immediately.
+	LockWaitSeconds int `toml:"lock_wait_seconds"`
 }
 
 type PathsConfig struct {
@@ -38,6 +62,178 @@ type PrefixConfig struct {
 	DownloadURL string `toml:"download_url"`
 }
 
+// Install strategies for runtime verbs in game prefixes.
+const (
+	InstallStrategyWinetricks = "winetricks"
+	InstallStrategyClone      = "clone"
+)
+
+// RuntimeConfig controls the dependencies installed into game prefixes.
+type RuntimeConfig struct {
+	Verbs []string `toml:"verbs"`
+	// InstallStrategy is "winetricks" or "clone". "clone" copies supported
+	// verbs from the own prefix and falls back to winetricks on failure.
+	InstallStrategy string `toml:"install_strategy"`
+}
+
+// CompatConfig controls how known-bad WeMod/Proton combinations are handled.
+type CompatConfig struct {
+	// Mode is "warn", "refuse" or "off".
+	Mode string `toml:"mode"`
+}
+
+// WeModConfig controls how the WeMod process is started.
+type WeModConfig struct {
+	// Args and Env are passed to every WeMod start. They are applied after
+	// the start profile, so they win over profile settings.
+	Args []string          `toml:"args"`
+	Env  map[string]string `toml:"env,omitempty"`
+	// Fallback restarts WeMod with the next profile of ProfileChain when its
+	// renderer does not come up.
+	Fallback     bool     `toml:"fallback"`
+	ProfileChain []string `toml:"profile_chain"`
+	// Profiles adds or replaces argument/env profiles by name.
+	Profiles map[string]WeModProfile `toml:"profiles,omitempty"`
+	// Watchdog restarts WeMod when it dies while the game is running.
+	Watchdog WatchdogConfig `toml:"watchdog"`
+	// Log controls the per-session log of WeMod's Wine output.
+	Log WeModLogConfig `toml:"log"`
+}
+
+// WeModLogConfig controls the WeMod output logs in <log dir>/wemod.
+type WeModLogConfig struct {
+	// Enabled writes WeMod's stdout/stderr to one file per launch;
+	// otherwise the output is discarded.
+	Enabled bool `toml:"enabled"`
+	// WineDebug is passed to WeMod as WINEDEBUG unless [wemod] env or the
+	// start profile sets it. Empty keeps Wine's default channels.
+	WineDebug string `toml:"winedebug"`
+	// Keep is the number of WeMod logs kept; older ones are deleted.
+	Keep int `toml:"keep"`
+	// MaxSizeMB caps each WeMod log; 0 disables the cap.
+	MaxSizeMB int `toml:"max_size_mb"`
+}
+
+// WatchdogConfig controls restarts of a crashed WeMod during a game.
+type WatchdogConfig struct {
+	Enabled     bool `toml:"enabled"`
+	MaxRestarts int  `toml:"max_restarts"`
+	// BackoffSeconds is the delay before the first restart; it doubles with
+	// every further restart up to MaxBackoffSeconds.
+	BackoffSeconds    int `toml:"backoff_seconds"`
+	MaxBackoffSeconds int `toml:"max_backoff_seconds"`
+}
+
+// Backoff returns the delay before the given restart (1-based).
+func (w WatchdogConfig) Backoff(restart int) time.Duration {
+	delay := time.Duration(max(w.BackoffSeconds, 1)) * time.Second
+	limit := time.Duration(max(w.MaxBackoffSeconds, w.BackoffSeconds, 1)) * time.Second
+	for i := 1; i < restart && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// WeModProfile is a set of extra WeMod arguments and environment variables.
+type WeModProfile struct {
+	Args []string          `toml:"args,omitempty"`
+	Env  map[string]string `toml:"env,omitempty"`
+}
+
+// ShutdownConfig controls how a game session is stopped when the launcher
+// gets SIGINT/SIGTERM/SIGHUP.
+type ShutdownConfig struct {
+	// GraceSeconds is how long the game may take to exit after the signal
+	// was forwarded before it is killed.
+	GraceSeconds int `toml:"grace_seconds"`
+	// KillWineserver runs "wineserver -k" for the prefix afterwards, which
+	// stops every remaining Wine process in it.
+	KillWineserver bool `toml:"kill_wineserver"`
+}
+
+// Grace returns shutdown.grace_seconds as a duration.
+func (s ShutdownConfig) Grace() time.Duration {
+	if s.GraceSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(s.GraceSeconds) * time.Second
+}
+
+// RedactConfig extends the built-in masking of secrets in log output
+// (values of *TOKEN*, *SECRET*, *PASSWORD*, *API_KEY*, ... keys and flags,
+// URL passwords, bearer tokens).
+type RedactConfig struct {
+	// Keys are additional name parts of env variables and flags whose
+	// values are masked, e.g. "STEAMGRID" or "licence".
+	Keys []string `toml:"keys"`
+	// Patterns are regular expressions whose matches are masked; with
+	// capture groups only the groups are masked.
+	Patterns []string `toml:"patterns"`
+}
+
+// DefaultHookTimeout limits each hook command unless hooks.timeout_seconds
+// is set.
+const DefaultHookTimeout = 60 * time.Second
+
+// HooksConfig lists shell commands run at launch stages. Each entry is run
+// with sh -c.
+type HooksConfig struct {
+	PreLaunch      []string `toml:"pre_launch,omitempty"`
+	PostWeModStart []string `toml:"post_wemod_start,omitempty"`
+	PostGameExit   []string `toml:"post_game_exit,omitempty"`
+	TimeoutSeconds int      `toml:"timeout_seconds,omitempty"`
+}
+
+// Timeout returns the per-command hook timeout.
+func (h HooksConfig) Timeout() time.Duration {
+	if h.TimeoutSeconds <= 0 {
+		return DefaultHookTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// BuiltinWeModProfiles are the Electron workarounds from
+// docs/wand-findings.md, from least to most invasive.
+func BuiltinWeModProfiles() map[string]WeModProfile {
+	safe := []string{"--disable-gpu", "--disable-gpu-compositing", "--use-angle=swiftshader"}
+	aggressive := append(append([]string{}, safe...), "--in-process-gpu", "--no-sandbox")
+	return map[string]WeModProfile{
+		"default":    {},
+		"safe":       {Args: safe},
+		"aggressive": {Args: aggressive},
+		"rescue": {
+			Args: aggressive,
+			Env:  map[string]string{"LIBGL_ALWAYS_SOFTWARE": "1", "GALLIUM_DRIVER": "llvmpipe"},
+		},
+	}
+}
+
+// DefaultWeModProfileChain is the fallback order of the built-in profiles.
+func DefaultWeModProfileChain() []string {
+	return []string{"default", "safe", "aggressive", "rescue"}
+}
+
+// GameConfig holds overrides for a single game. Empty values fall back to
+// the global settings.
+type GameConfig struct {
+	Verbs           []string `toml:"verbs,omitempty"`
+	InstallStrategy string   `toml:"install_strategy,omitempty"`
+	// Prefix and Wine run WeMod in a plain Wine prefix with a specific wine
+	// build (binary or build directory), like launch --prefix/--wine.
+	Prefix string `toml:"prefix,omitempty"`
+	Wine   string `toml:"wine,omitempty"`
+	// WeMod adds arguments and env variables for this game on top of the
+	// global [wemod] args/env.
+	WeMod WeModProfile `toml:"wemod,omitempty"`
+	// Hooks run after the global hooks of the same stage.
+	Hooks HooksConfig `toml:"hooks,omitempty"`
+}
+
+// DefaultRuntimeVerbs are the winetricks verbs WeMod needs in a prefix.
+func DefaultRuntimeVerbs() []string {
+	return []string{"corefonts", "dotnet48"}
+}
+
 func defaultConfigPath() (string, error) {
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome == "" {
@@ -60,12 +256,30 @@ func Default() (*Config, error) {
 	cfg := &Config{}
 	cfg.General.Interactive = true
 	cfg.General.LogLevel = "info"
+	cfg.General.LogFormat = "text"
 	cfg.General.LogFile = filepath.Join(baseDir, "wemod-launcher.log")
+	cfg.General.LogMaxSizeMB = 10
+	cfg.General.LogKeep = 5
+	cfg.General.LogMaxAgeDays = 30
+	cfg.General.LogCompress = true
 	cfg.Paths.WorkDir = baseDir
 	cfg.Paths.WeModExePath = filepath.Join(baseDir, "wemod_bin", "WeMod.exe")
 	cfg.Paths.PrefixDir = filepath.Join(baseDir, "wemod_prefix")
 	cfg.Paths.DownloadDir = filepath.Join(baseDir, "downloads")
 	cfg.Prefix.DownloadURL = "auto"
+	cfg.Runtime.Verbs = DefaultRuntimeVerbs()
+	cfg.Runtime.InstallStrategy = InstallStrategyWinetricks
+	cfg.Compat.Mode = "warn"
+	cfg.WeMod.Fallback = true
+	cfg.WeMod.ProfileChain = DefaultWeModProfileChain()
+	cfg.Shutdown.GraceSeconds = 10
+	cfg.WeMod.Watchdog.MaxRestarts = 3
+	cfg.WeMod.Watchdog.BackoffSeconds = 5
+	cfg.WeMod.Watchdog.MaxBackoffSeconds = 60
+	cfg.WeMod.Log.Enabled = true
+	cfg.WeMod.Log.WineDebug = "fixme-all"
+	cfg.WeMod.Log.Keep = 10
+	cfg.WeMod.Log.MaxSizeMB = 50
 	return cfg, nil
 }
 
@@ -112,6 +326,150 @@ func LoadOrCreate(path string) (*Config, string, error) {
 	return cfg, path, nil
 }
 
+// Game returns the overrides for the given game ID. Unknown or empty IDs
+// return an empty GameConfig.
+func (c *Config) Game(id string) GameConfig {
+	id = strings.TrimSpace(id)
+	if id == "" || c.Games == nil {
+		return GameConfig{}
+	}
+	return c.Games[id]
+}
+
+// RuntimeVerbs returns the winetricks verbs required for the given game.
+// A non-empty per-game list replaces the global runtime.verbs list.
+func (c *Config) RuntimeVerbs(gameID string) []string {
+	verbs := c.Game(gameID).Verbs
+	if len(verbs) == 0 {
+		verbs = c.Runtime.Verbs
+	}
+	if len(verbs) == 0 {
+		verbs = DefaultRuntimeVerbs()
+	}
+	return normalizeVerbs(verbs)
+}
+
+// RuntimeInstallStrategy returns the install strategy for the given game.
+// Unknown values fall back to winetricks.
+func (c *Config) RuntimeInstallStrategy(gameID string) string {
+	strategy := strings.ToLower(strings.TrimSpace(c.Game(gameID).InstallStrategy))
+	if strategy == "" {
+		strategy = strings.ToLower(strings.TrimSpace(c.Runtime.InstallStrategy))
+	}
+	if strategy != InstallStrategyClone {
+		return InstallStrategyWinetricks
+	}
+	return strategy
+}
+
+// LockWait returns general.lock_wait_seconds as a duration.
+func (c *Config) LockWait() time.Duration {
+	if c.General.LockWaitSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.General.LockWaitSeconds) * time.Second
+}
+
+// LogDir returns the directory of the launcher log, or <work_dir>/logs when
+// file logging is disabled.
+func (c *Config) LogDir() string {
+	if c.General.LogFile != "" {
+		return filepath.Dir(c.General.LogFile)
+	}
+	return filepath.Join(c.Paths.WorkDir, "logs")
+}
+
+// WeModLogDir returns the directory of the per-session WeMod logs.
+func (c *Config) WeModLogDir() string {
+	return filepath.Join(c.LogDir(), "wemod")
+}
+
+// CompatMode returns compat.mode. Unknown values fall back to "warn".
+func (c *Config) CompatMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(c.Compat.Mode)); mode {
+	case "refuse", "off":
+		return mode
+	}
+	return "warn"
+}
+
+// WeModProfile returns the named profile. Profiles from the config replace
+// built-in profiles of the same name.
+func (c *Config) WeModProfile(name string) (WeModProfile, bool) {
+	if profile, ok := c.WeMod.Profiles[name]; ok {
+		return profile, true
+	}
+	profile, ok := BuiltinWeModProfiles()[name]
+	return profile, ok
+}
+
+// WeModSettings returns the WeMod arguments and env from [wemod] merged with
+// [games.<id>.wemod]: per-game arguments are appended, per-game env values
+// replace global ones.
+func (c *Config) WeModSettings(gameID string) WeModProfile {
+	game := c.Game(gameID).WeMod
+	settings := WeModProfile{
+		Args: append(append([]string{}, c.WeMod.Args...), game.Args...),
+		Env:  map[string]string{},
+	}
+	for key, value := range c.WeMod.Env {
+		settings.Env[key] = value
+	}
+	for key, value := range game.Env {
+		settings.Env[key] = value
+	}
+	return settings
+}
+
+// GameHooks returns the global hooks followed by the hooks of the given game. A
+// per-game timeout replaces the global one.
+func (c *Config) GameHooks(gameID string) HooksConfig {
+	game := c.Game(gameID).Hooks
+	hooks := HooksConfig{
+		PreLaunch:      append(append([]string{}, c.Hooks.PreLaunch...), game.PreLaunch...),
+		PostWeModStart: append(append([]string{}, c.Hooks.PostWeModStart...), game.PostWeModStart...),
+		PostGameExit:   append(append([]string{}, c.Hooks.PostGameExit...), game.PostGameExit...),
+		TimeoutSeconds: c.Hooks.TimeoutSeconds,
+	}
+	if game.TimeoutSeconds > 0 {
+		hooks.TimeoutSeconds = game.TimeoutSeconds
+	}
+	return hooks
+}
+
+// WeModProfileChain returns the configured profile names that exist, in
+// order. An empty or fully unknown chain yields the built-in "default".
+func (c *Config) WeModProfileChain() []string {
+	seen := map[string]bool{}
+	chain := make([]string, 0, len(c.WeMod.ProfileChain))
+	for _, name := range c.WeMod.ProfileChain {
+		name = strings.TrimSpace(name)
+		if _, ok := c.WeModProfile(name); !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		chain = append(chain, name)
+	}
+	if len(chain) == 0 {
+		chain = append(chain, "default")
+	}
+	return chain
+}
+
+func normalizeVerbs(verbs []string) []string {
+	seen := make(map[string]bool, len(verbs))
+	normalized := make([]string, 0, len(verbs))
+	for _, verb := range verbs {
+		verb = strings.ToLower(strings.TrimSpace(verb))
+		if verb == "" || seen[verb] {
+			continue
+		}
+		seen[verb] = true
+		normalized = append(normalized, verb)
+	}
+	return normalized
+}
+
 func (c *Config) Save(path string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
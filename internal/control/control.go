@@ -0,0 +1,208 @@
+// Package control exposes a running launch on a Unix socket so it can be
+// inspected and controlled with "wemod ctl".
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+// Commands accepted on the control socket.
+const (
+	CommandStatus       = "status"
+	CommandRestartWeMod = "restart-wemod"
+	CommandStopWeMod    = "stop-wemod"
+	CommandSyncNow      = "sync-now"
+)
+
+// Commands lists the control commands in help order.
+var Commands = []string{CommandStatus, CommandRestartWeMod, CommandStopWeMod, CommandSyncNow}
+
+const (
+	socketPrefix = "launcher-"
+	socketSuffix = ".sock"
+	// requestTimeout bounds reading a request; restarts are not bounded here
+	// because the WeMod startup observation alone takes up to 30 seconds.
+	requestTimeout = 5 * time.Second
+)
+
+// Request is one command sent to the socket.
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response answers a request. Status is only set for "status".
+type Response struct {
+	OK      bool              `json:"ok"`
+	Message string            `json:"message,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Status  *registry.Session `json:"status,omitempty"`
+}
+
+// Handler implements the control commands for a launch.
+type Handler interface {
+	Status() registry.Session
+	RestartWeMod(ctx context.Context) (string, error)
+	StopWeMod() (string, error)
+	SyncNow() (string, error)
+}
+
+// SocketPath returns the control socket of the launcher process pid.
+func SocketPath(pid int) string {
+	return filepath.Join(registry.RuntimeDir(), socketPrefix+strconv.Itoa(pid)+socketSuffix)
+}
+
+// Server serves control requests on a Unix socket.
+type Server struct {
+	path     string
+	listener net.Listener
+}
+
+// Listen creates the control socket of the current process.
+func Listen() (*Server, error) {
+	path := SocketPath(os.Getpid())
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create runtime dir: %w", err)
+	}
+	_ = os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restrict control socket: %w", err)
+	}
+	return &Server{path: path, listener: listener}, nil
+}
+
+// Path returns the socket path.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve accepts connections until the server is closed. Each connection
+// carries one JSON request and gets one JSON response.
+func (s *Server) Serve(ctx context.Context, logger *logging.Logger, handler Handler) {
+	logger = logger.WithComponent("control")
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Warn("control socket accept failed: %v", err)
+			}
+			return
+		}
+		go s.handle(ctx, logger, conn, handler)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, logger *logging.Logger, conn net.Conn, handler Handler) {
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	var request Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&request); err != nil {
+		logger.Warn("invalid control request: %v", err)
+		_ = json.NewEncoder(conn).Encode(Response{Error: "invalid request"})
+		return
+	}
+	logger.Info("control command received: %s", request.Command)
+	response := dispatch(ctx, handler, request.Command)
+	if !response.OK {
+		logger.Warn("control command %s failed: %s", request.Command, response.Error)
+	}
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		logger.Warn("failed writing control response: %v", err)
+	}
+}
+
+func dispatch(ctx context.Context, handler Handler, command string) Response {
+	var message string
+	var err error
+	switch command {
+	case CommandStatus:
+		status := handler.Status()
+		return Response{OK: true, Status: &status}
+	case CommandRestartWeMod:
+		message, err = handler.RestartWeMod(ctx)
+	case CommandStopWeMod:
+		message, err = handler.StopWeMod()
+	case CommandSyncNow:
+		message, err = handler.SyncNow()
+	default:
+		return Response{Error: fmt.Sprintf("unknown command %q (valid: %s)", command, strings.Join(Commands, "|"))}
+	}
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{OK: true, Message: message}
+}
+
+// Close stops accepting requests and removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	if removeErr := os.Remove(s.path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) && err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Send sends one command to the socket at path and returns the response.
+func Send(path, command string, timeout time.Duration) (Response, error) {
+	var response Response
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return response, fmt.Errorf("connect to %s: %w", path, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if err := json.NewEncoder(conn).Encode(Request{Command: command}); err != nil {
+		return response, fmt.Errorf("send %s: %w", command, err)
+	}
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return response, fmt.Errorf("read %s response: %w", command, err)
+	}
+	return response, nil
+}
+
+// Sockets returns the control sockets of running launcher processes by pid.
+// Sockets left behind by launchers that died are removed.
+func Sockets() (map[int]string, error) {
+	entries, err := os.ReadDir(registry.RuntimeDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return map[int]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read runtime dir: %w", err)
+	}
+	sockets := map[int]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, socketPrefix) || !strings.HasSuffix(name, socketSuffix) {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, socketPrefix), socketSuffix))
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(registry.RuntimeDir(), name)
+		if !registry.Alive(pid, "") {
+			_ = os.Remove(path)
+			continue
+		}
+		sockets[pid] = path
+	}
+	return sockets, nil
+}
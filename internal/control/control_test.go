@@ -0,0 +1,107 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+type fakeHandler struct {
+	restarts int
+}
+
+func (h *fakeHandler) Status() registry.Session {
+	return registry.Session{PID: os.Getpid(), GameID: "1245620", Prefix: "/tmp/pfx", WeModPID: 42}
+}
+
+func (h *fakeHandler) RestartWeMod(ctx context.Context) (string, error) {
+	h.restarts++
+	return "restarted", nil
+}
+
+func (h *fakeHandler) StopWeMod() (string, error) {
+	return "", errors.New("WeMod is not running")
+}
+
+func (h *fakeHandler) SyncNow() (string, error) {
+	return "synced", nil
+}
+
+func newTestLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "error"
+	logger, err := logging.New(cfg)
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestServer_Commands(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	server, err := Listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer server.Close()
+	handler := &fakeHandler{}
+	go server.Serve(context.Background(), newTestLogger(t), handler)
+
+	response, err := Send(server.Path(), CommandStatus, time.Second)
+	if err != nil {
+		t.Fatalf("send status: %v", err)
+	}
+	if !response.OK || response.Status == nil || response.Status.WeModPID != 42 || response.Status.GameID != "1245620" {
+		t.Fatalf("unexpected status response: %+v", response)
+	}
+
+	if response, err = Send(server.Path(), CommandRestartWeMod, time.Second); err != nil || !response.OK || response.Message != "restarted" {
+		t.Fatalf("unexpected restart response: %+v (%v)", response, err)
+	}
+	if handler.restarts != 1 {
+		t.Fatalf("expected one restart, got %d", handler.restarts)
+	}
+	if response, err = Send(server.Path(), CommandStopWeMod, time.Second); err != nil || response.OK || response.Error != "WeMod is not running" {
+		t.Fatalf("unexpected stop response: %+v (%v)", response, err)
+	}
+	if response, err = Send(server.Path(), "reboot", time.Second); err != nil || response.OK {
+		t.Fatalf("unknown command must fail: %+v (%v)", response, err)
+	}
+}
+
+func TestSockets_RemovesStale(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	server, err := Listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer server.Close()
+	// PIDs above the kernel maximum (4194304) can never be running.
+	stale := SocketPath(99999999)
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatalf("write stale socket: %v", err)
+	}
+
+	sockets, err := Sockets()
+	if err != nil {
+		t.Fatalf("sockets: %v", err)
+	}
+	if len(sockets) != 1 || sockets[os.Getpid()] != server.Path() {
+		t.Fatalf("unexpected sockets: %v", sockets)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stale socket not removed: %v", err)
+	}
+	if filepath.Dir(server.Path()) != registry.RuntimeDir() {
+		t.Fatalf("socket outside runtime dir: %s", server.Path())
+	}
+}
@@ -0,0 +1,125 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+// ctlTimeout covers a WeMod restart including the startup observation.
+const ctlTimeout = 3 * time.Minute
+
+// Ctl implements "ctl [--pid <pid>] <command>". Without --pid the command
+// goes to the only running launcher.
+func Ctl(logger *logging.Logger, args []string) error {
+	logger = logger.WithComponent("control.ctl")
+	usage := fmt.Errorf("usage: wemod-launcher ctl [--pid <pid>] <%s>", strings.Join(Commands, "|"))
+
+	pid := 0
+	command := ""
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--pid" && i+1 < len(args):
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return usage
+			}
+			pid = value
+			i++
+		case strings.HasPrefix(arg, "--pid="):
+			value, err := strconv.Atoi(strings.TrimPrefix(arg, "--pid="))
+			if err != nil {
+				return usage
+			}
+			pid = value
+		case command == "" && !strings.HasPrefix(arg, "-"):
+			command = arg
+		default:
+			return usage
+		}
+	}
+	if command == "" {
+		return usage
+	}
+
+	path, err := selectSocket(pid)
+	if err != nil {
+		return err
+	}
+	logger.Debug("sending %s to %s", command, path)
+	response, err := Send(path, command, ctlTimeout)
+	if err != nil {
+		return err
+	}
+	if !response.OK {
+		return errors.New(response.Error)
+	}
+	if response.Status != nil {
+		printStatus(*response.Status)
+		return nil
+	}
+	fmt.Println(response.Message)
+	return nil
+}
+
+func selectSocket(pid int) (string, error) {
+	sockets, err := Sockets()
+	if err != nil {
+		return "", err
+	}
+	if pid != 0 {
+		path, ok := sockets[pid]
+		if !ok {
+			return "", fmt.Errorf("no running launcher with pid %d", pid)
+		}
+		return path, nil
+	}
+	switch len(sockets) {
+	case 0:
+		return "", errors.New("no running launcher found")
+	case 1:
+		for _, path := range sockets {
+			return path, nil
+		}
+	}
+	pids := make([]string, 0, len(sockets))
+	for pid := range sockets {
+		pids = append(pids, strconv.Itoa(pid))
+	}
+	sort.Strings(pids)
+	return "", fmt.Errorf("several launchers are running (pids %s); select one with --pid", strings.Join(pids, ", "))
+}
+
+func printStatus(status registry.Session) {
+	fmt.Printf("Launcher PID:  %d (running since %s)\n", status.PID, status.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Session ID:    %s\n", valueOrDash(status.SessionID))
+	fmt.Printf("Game ID:       %s\n", valueOrDash(status.GameID))
+	fmt.Printf("Source:        %s\n", status.Source)
+	fmt.Printf("Prefix:        %s\n", status.Prefix)
+	fmt.Printf("Proton:        %s\n", valueOrDash(status.Proton))
+	fmt.Printf("Wine:          %s\n", valueOrDash(status.Wine))
+	fmt.Printf("Game command:  %s\n", valueOrDash(strings.Join(status.GameCommand, " ")))
+	fmt.Printf("Game PID:      %s\n", pidOrDash(status.GamePID))
+	fmt.Printf("WeMod PID:     %s\n", pidOrDash(status.WeModPID))
+	fmt.Printf("WeMod profile: %s\n", valueOrDash(status.WeModProfile))
+}
+
+func valueOrDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+func pidOrDash(pid int) string {
+	if pid == 0 {
+		return "-"
+	}
+	return strconv.Itoa(pid)
+}
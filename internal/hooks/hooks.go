@@ -0,0 +1,123 @@
+// Package hooks runs the user-configured shell commands around a launch.
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+)
+
+// Hook stages.
+const (
+	PreLaunch      = "pre_launch"
+	PostWeModStart = "post_wemod_start"
+	PostGameExit   = "post_game_exit"
+)
+
+// Environment variables passed to hook commands.
+const (
+	EnvHook          = "WEMOD_HOOK"
+	EnvGameID        = "WEMOD_GAME_ID"
+	EnvSource        = "WEMOD_SOURCE"
+	EnvPrefix        = "WEMOD_PREFIX"
+	EnvProton        = "WEMOD_PROTON"
+	EnvProtonVersion = "WEMOD_PROTON_VERSION"
+	EnvProtonFlavor  = "WEMOD_PROTON_FLAVOR"
+	EnvWine          = "WEMOD_WINE"
+	EnvWeModPID      = "WEMOD_PID"
+	EnvGamePID       = "WEMOD_GAME_PID"
+	EnvGameExitCode  = "WEMOD_GAME_EXIT_CODE"
+)
+
+// killGrace is how long a timed out hook may take to release its output
+// after its process group was killed.
+const killGrace = 2 * time.Second
+
+// Run executes the commands of a hook stage one after another with sh -c.
+// Each command gets the hook timeout; output is written to the log. The
+// first failing command stops the stage and its error is returned.
+func Run(ctx context.Context, logger *logging.Logger, stage string, hooks config.HooksConfig, env map[string]string) error {
+	logger = logger.WithComponent("hooks")
+	commands := stageCommands(hooks, stage)
+	if len(commands) == 0 {
+		return nil
+	}
+	timeout := hooks.Timeout()
+	runEnv := make(map[string]string, len(env)+1)
+	for key, value := range env {
+		runEnv[key] = value
+	}
+	runEnv[EnvHook] = stage
+	logger.Debug("%s hook env: %s", stage, logging.FormatEnv(runEnv))
+
+	for _, command := range commands {
+		logger.Info("running %s hook: %s (timeout %s)", stage, command, timeout)
+		if err := runCommand(ctx, logger, command, runEnv, timeout); err != nil {
+			logger.Warn("%s hook failed: %s: %v", stage, command, err)
+			return fmt.Errorf("%s hook %q: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+// stageCommands returns the configured commands of a stage, skipping empty
+// entries.
+func stageCommands(hooks config.HooksConfig, stage string) []string {
+	var commands []string
+	switch stage {
+	case PreLaunch:
+		commands = hooks.PreLaunch
+	case PostWeModStart:
+		commands = hooks.PostWeModStart
+	case PostGameExit:
+		commands = hooks.PostGameExit
+	}
+	result := make([]string, 0, len(commands))
+	for _, command := range commands {
+		if strings.TrimSpace(command) != "" {
+			result = append(result, command)
+		}
+	}
+	return result
+}
+
+func runCommand(ctx context.Context, logger *logging.Logger, command string, env map[string]string, timeout time.Duration) error {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(runCtx, "sh", "-c", command)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Hooks run in their own process group so a timeout also stops the
+	// processes they spawned.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = killGrace
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	err := cmd.Run()
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		logger.Info("hook output: %s", scanner.Text())
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
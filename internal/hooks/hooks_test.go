@@ -0,0 +1,66 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+)
+
+func newTestLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "error"
+	logger, err := logging.New(cfg)
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestRun_PassesEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env.txt")
+	hooks := config.HooksConfig{
+		PostGameExit: []string{`echo "$WEMOD_HOOK $WEMOD_GAME_ID $WEMOD_GAME_EXIT_CODE" > "$OUT"`},
+	}
+	env := map[string]string{EnvGameID: "1245620", EnvGameExitCode: "3", "OUT": out}
+	if err := Run(context.Background(), newTestLogger(t), PostGameExit, hooks, env); err != nil {
+		t.Fatalf("run hook: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read hook output: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "post_game_exit 1245620 3" {
+		t.Fatalf("unexpected hook env: %q", got)
+	}
+}
+
+func TestRun_StopsOnFailure(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "second")
+	hooks := config.HooksConfig{PreLaunch: []string{"exit 4", "touch " + marker}}
+	if err := Run(context.Background(), newTestLogger(t), PreLaunch, hooks, nil); err == nil {
+		t.Fatal("expected failing hook to return an error")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("commands after a failing hook must not run")
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	hooks := config.HooksConfig{PostWeModStart: []string{"sleep 30 & wait"}, TimeoutSeconds: 1}
+	started := time.Now()
+	err := Run(context.Background(), newTestLogger(t), PostWeModStart, hooks, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Fatalf("hook timeout took too long: %s", elapsed)
+	}
+}
@@ -0,0 +1,118 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/proton"
+)
+
+// CheckPrefix prints the runtime marker of a game prefix and whether it is
+// still valid. The argument is a Steam AppID or a prefix/compatdata directory.
+func CheckPrefix(cfg *config.Config, logger *logging.Logger, args []string) error {
+	logger = logger.WithComponent("launch.check")
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		return errors.New("usage: wemod-launcher prefix check <appid|prefix dir>")
+	}
+
+	prefixPath, gameID, err := resolveCheckTarget(strings.TrimSpace(args[0]))
+	if err != nil {
+		logger.Error("prefix check failed: %v", err)
+		return err
+	}
+	logger.Info("checking runtime marker in %s", prefixPath)
+
+	markerPath := RuntimeMarkerPath(prefixPath)
+	required := cfg.RuntimeVerbs(gameID)
+
+	fmt.Printf("Prefix:           %s\n", prefixPath)
+	fmt.Printf("Marker:           %s\n", markerPath)
+	fmt.Printf("Required verbs:   %s\n", strings.Join(required, ", "))
+
+	marker, err := readRuntimeMarker(markerPath)
+	if err != nil {
+		logger.Error("failed reading runtime marker %s: %v", markerPath, err)
+		return err
+	}
+	if marker == nil {
+		fmt.Println("Status:           not prepared (no runtime marker)")
+		return nil
+	}
+
+	fmt.Printf("Schema version:   %d\n", marker.SchemaVersion)
+	fmt.Printf("Launcher version: %s\n", valueOrDash(marker.LauncherVersion))
+	fmt.Printf("Proton path:      %s\n", valueOrDash(marker.ProtonPath))
+	fmt.Printf("Proton version:   %s\n", valueOrDash(marker.ProtonVersion))
+	if marker.ProtonPath != "" {
+		info := proton.Inspect(marker.ProtonPath)
+		fmt.Printf("Proton build:     %s\n", info)
+		fmt.Printf("Proton wine:      %s\n", valueOrDash(info.WineBinary))
+	}
+	fmt.Printf("Prefix version:   %s\n", valueOrDash(marker.PrefixVersion))
+	fmt.Printf("Created:          %s\n", formatMarkerTime(marker.CreatedAt))
+	fmt.Printf("Updated:          %s\n", formatMarkerTime(marker.UpdatedAt))
+	fmt.Println("Verified verbs:")
+	if len(marker.Verbs) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, verb := range marker.sortedVerbs() {
+		fmt.Printf("  %-14s %s\n", verb, formatMarkerTime(marker.Verbs[verb]))
+	}
+
+	current := runtimeMarker{
+		LauncherVersion: config.AppVersion,
+		PrefixVersion:   readCompatDataVersion(prefixPath),
+	}
+	reasons := marker.staleReasons(current)
+	if missing := marker.missingVerbs(required); len(missing) > 0 {
+		reasons = append(reasons, fmt.Sprintf("missing verbs %s", strings.Join(missing, ", ")))
+	}
+	if len(reasons) > 0 {
+		fmt.Printf("Status:           revalidation on next launch (%s)\n", strings.Join(reasons, "; "))
+		return nil
+	}
+	fmt.Println("Status:           up to date")
+	return nil
+}
+
+// resolveCheckTarget maps a prefix check argument to a prefix directory and
+// the game ID used for per-game config lookup.
+func resolveCheckTarget(target string) (string, string, error) {
+	if st, err := os.Stat(target); err == nil && st.IsDir() {
+		prefixPath := target
+		if st, err := os.Stat(filepath.Join(target, "pfx")); err == nil && st.IsDir() {
+			prefixPath = filepath.Join(target, "pfx")
+		}
+		gameID := ""
+		if filepath.Base(filepath.Clean(prefixPath)) == "pfx" {
+			gameID = filepath.Base(filepath.Dir(filepath.Clean(prefixPath)))
+		}
+		return prefixPath, gameID, nil
+	}
+
+	prefixPath, err := findCompatDataPrefix(target)
+	if err != nil {
+		return "", "", err
+	}
+	return prefixPath, target, nil
+}
+
+func valueOrDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+func formatMarkerTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05 MST")
+}
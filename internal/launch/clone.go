@@ -0,0 +1,202 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/winereg"
+)
+
+// cloneSpec lists what a winetricks verb leaves behind in a prefix, so it can
+// be copied from the own prefix instead of being reinstalled.
+type cloneSpec struct {
+	// paths are relative to drive_c/windows. Directories are copied recursively.
+	paths []string
+	// systemKeys are registry key prefixes copied from system.reg.
+	systemKeys []string
+	// valueFilter restricts which values of matching keys are copied. Nil copies all.
+	valueFilter func(value winereg.Value) bool
+	// dllOverrides are written into user.reg Software\Wine\DllOverrides.
+	dllOverrides map[string]string
+}
+
+var corefontsFiles = []string{
+	"andalemo.ttf", "arial.ttf", "arialbd.ttf", "arialbi.ttf", "ariali.ttf", "ariblk.ttf",
+	"comic.ttf", "comicbd.ttf", "cour.ttf", "courbd.ttf", "courbi.ttf", "couri.ttf",
+	"georgia.ttf", "georgiab.ttf", "georgiai.ttf", "georgiaz.ttf", "impact.ttf",
+	"times.ttf", "timesbd.ttf", "timesbi.ttf", "timesi.ttf",
+	"trebuc.ttf", "trebucbd.ttf", "trebucbi.ttf", "trebucit.ttf",
+	"verdana.ttf", "verdanab.ttf", "verdanai.ttf", "verdanaz.ttf", "webdings.ttf",
+}
+
+var cloneSpecs = map[string]cloneSpec{
+	"dotnet48": {
+		paths: []string{
+			"Microsoft.NET",
+			"assembly",
+			filepath.Join("system32", "mscoree.dll"),
+			filepath.Join("syswow64", "mscoree.dll"),
+		},
+		systemKeys: []string{
+			`Software\Microsoft\.NETFramework`,
+			`Software\Microsoft\NET Framework Setup`,
+			`Software\Wow6432Node\Microsoft\.NETFramework`,
+			`Software\Wow6432Node\Microsoft\NET Framework Setup`,
+		},
+		dllOverrides: map[string]string{"mscoree": "native"},
+	},
+	"corefonts": {
+		paths: corefontsPaths(),
+		systemKeys: []string{
+			`Software\Microsoft\Windows NT\CurrentVersion\Fonts`,
+			`Software\Microsoft\Windows\CurrentVersion\Fonts`,
+		},
+		valueFilter: func(value winereg.Value) bool {
+			text, ok := value.Text()
+			if !ok {
+				return false
+			}
+			lower := strings.ToLower(text)
+			for _, font := range corefontsFiles {
+				if strings.Contains(lower, font) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+}
+
+func corefontsPaths() []string {
+	paths := make([]string, 0, len(corefontsFiles))
+	for _, font := range corefontsFiles {
+		paths = append(paths, filepath.Join("Fonts", font))
+	}
+	return paths
+}
+
+// canCloneVerb reports whether verb can be copied from the own prefix.
+func canCloneVerb(verb string) bool {
+	_, ok := cloneSpecs[verb]
+	return ok
+}
+
+// cloneVerbFromPrefix copies the files and registry entries of verb from the
+// own prefix into the game prefix. system.reg and user.reg are both loaded
+// and edited before either is written; each is backed up before it is
+// replaced.
+func cloneVerbFromPrefix(logger *logging.Logger, ownPrefix, gamePrefix, verb string) error {
+	logger = logger.WithComponent("launch.clone")
+	spec, ok := cloneSpecs[verb]
+	if !ok {
+		return fmt.Errorf("verb %s cannot be cloned", verb)
+	}
+
+	ownSystem, err := winereg.Load(filepath.Join(ownPrefix, "system.reg"))
+	if err != nil {
+		return fmt.Errorf("read own prefix registry: %w", err)
+	}
+	gameSystem, err := winereg.Load(filepath.Join(gamePrefix, "system.reg"))
+	if err != nil {
+		return fmt.Errorf("read game prefix registry: %w", err)
+	}
+	if ownArch, gameArch := ownSystem.Arch(), gameSystem.Arch(); ownArch != gameArch {
+		return fmt.Errorf("prefix architecture mismatch (own=%s game=%s)", ownArch, gameArch)
+	}
+
+	ownWindows := filepath.Join(ownPrefix, "drive_c", "windows")
+	gameWindows := filepath.Join(gamePrefix, "drive_c", "windows")
+	for _, rel := range spec.paths {
+		src := filepath.Join(ownWindows, rel)
+		st, err := os.Stat(src)
+		if errors.Is(err, os.ErrNotExist) && filepath.Dir(rel) == "syswow64" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s not found in own prefix: %w", rel, err)
+		}
+		dst := filepath.Join(gameWindows, rel)
+		logger.Debug("copying %s -> %s", src, dst)
+		if st.IsDir() {
+			err = copyDir(src, dst)
+		} else {
+			err = copyFile(src, dst, st.Mode())
+		}
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", rel, err)
+		}
+	}
+
+	copied := mergeRegistryKeys(gameSystem, ownSystem, spec.systemKeys, spec.valueFilter)
+	if copied == 0 {
+		return fmt.Errorf("no %s registry keys found in own prefix", verb)
+	}
+	logger.Debug("merged %d registry keys for %s into game system.reg", copied, verb)
+
+	userRegPath := filepath.Join(gamePrefix, "user.reg")
+	var gameUser *winereg.File
+	if len(spec.dllOverrides) > 0 {
+		gameUser, err = winereg.Load(userRegPath)
+		if err != nil {
+			return fmt.Errorf("read game prefix user registry: %w", err)
+		}
+		overrides := gameUser.CreateKey(`Software\Wine\DllOverrides`)
+		for dll, mode := range spec.dllOverrides {
+			overrides.SetValue(winereg.StringValue(dll, mode))
+		}
+	}
+
+	if err := writeRegFileWithBackup(filepath.Join(gamePrefix, "system.reg"), gameSystem); err != nil {
+		return err
+	}
+	if gameUser != nil {
+		if err := writeRegFileWithBackup(userRegPath, gameUser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRegistryKeys copies all keys of src below any of the key prefixes into
+// dst. Keys missing in dst are copied verbatim unless a filter is set;
+// existing keys are merged value by value. It returns the number of source
+// keys that matched.
+func mergeRegistryKeys(dst, src *winereg.File, prefixes []string, filter func(winereg.Value) bool) int {
+	matched := 0
+	for _, prefix := range prefixes {
+		for _, key := range src.KeysUnder(prefix) {
+			matched++
+			if filter == nil {
+				dst.ImportKey(key)
+				continue
+			}
+			for _, value := range key.Values() {
+				if filter(value) {
+					dst.CreateKey(key.Name()).SetValue(value)
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// writeRegFileWithBackup keeps a one-time backup of the original registry file
+// and replaces it atomically.
+func writeRegFileWithBackup(path string, file *winereg.File) error {
+	backup := path + ".wemod-launcher.bak"
+	if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+		st, statErr := os.Stat(path)
+		if statErr != nil {
+			return fmt.Errorf("stat %s: %w", path, statErr)
+		}
+		if err := copyFile(path, backup, st.Mode()); err != nil {
+			return fmt.Errorf("backup %s: %w", path, err)
+		}
+	}
+
+	return file.Save(path)
+}
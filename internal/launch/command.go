@@ -0,0 +1,183 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of game commands recognized by inspectGameCommand.
+const (
+	commandDirect       = "direct"
+	commandProton       = "proton"
+	commandSteamRuntime = "steam-runtime"
+	commandUmu          = "umu"
+)
+
+// protonVerbs are the proton script verbs used to run a game.
+var protonVerbs = map[string]bool{
+	"waitforexitandrun": true,
+	"run":               true,
+	"runinprefix":       true,
+}
+
+// protonCommand describes how a game command invokes Proton.
+type protonCommand struct {
+	kind string
+	// protonPath is the proton script. It may be empty for umu commands whose
+	// Proton build is not installed yet.
+	protonPath string
+	// protonIndex is the position of the proton script in the command, or -1.
+	protonIndex int
+}
+
+// inspectGameCommand recognizes plain Proton calls, Proton behind the Steam
+// Linux Runtime (pressure-vessel) entry points, and umu-launcher commands.
+func inspectGameCommand(gameCmd []string) protonCommand {
+	for i := 0; i+1 < len(gameCmd); i++ {
+		if !isProtonScript(gameCmd[i]) || !protonVerbs[gameCmd[i+1]] {
+			continue
+		}
+		kind := commandProton
+		for _, wrapper := range gameCmd[:i] {
+			if isSteamRuntimeEntryPoint(wrapper) {
+				kind = commandSteamRuntime
+				break
+			}
+		}
+		return protonCommand{kind: kind, protonPath: gameCmd[i], protonIndex: i}
+	}
+
+	for _, arg := range gameCmd {
+		if isUmuRun(arg) {
+			return protonCommand{kind: commandUmu, protonPath: resolveUmuProtonPath(), protonIndex: -1}
+		}
+	}
+	return protonCommand{kind: commandDirect, protonIndex: -1}
+}
+
+// isProtonCommand reports whether the game command runs through Proton.
+func isProtonCommand(gameCmd []string) bool {
+	return inspectGameCommand(gameCmd).kind != commandDirect
+}
+
+// protonPathOf returns the proton script used by the game command, or "".
+func protonPathOf(gameCmd []string) string {
+	return inspectGameCommand(gameCmd).protonPath
+}
+
+func isProtonScript(arg string) bool {
+	return strings.Contains(strings.ToLower(filepath.Base(arg)), "proton")
+}
+
+// isSteamRuntimeEntryPoint matches the Steam Linux Runtime launchers Steam
+// puts in front of Proton, e.g. SteamLinuxRuntime_sniper/_v2-entry-point or
+// SteamLinuxRuntime_sniper/run.
+func isSteamRuntimeEntryPoint(arg string) bool {
+	base := filepath.Base(arg)
+	if base == "_v2-entry-point" || strings.Contains(arg, "pressure-vessel") {
+		return true
+	}
+	parent := filepath.Base(filepath.Dir(arg))
+	return strings.HasPrefix(parent, "SteamLinuxRuntime") && (base == "run" || strings.HasPrefix(base, "run-in-"))
+}
+
+func isUmuRun(arg string) bool {
+	base := filepath.Base(arg)
+	return base == "umu-run" || base == "umu_run.py"
+}
+
+// resolveUmuPrefix mirrors umu-launcher's prefix selection: WINEPREFIX, or
+// ~/Games/umu/<GAMEID> when unset.
+func resolveUmuPrefix() string {
+	if prefix := strings.TrimSpace(os.Getenv("WINEPREFIX")); prefix != "" {
+		return prefix
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	gameID := strings.TrimSpace(os.Getenv("GAMEID"))
+	if gameID == "" {
+		gameID = "umu-default"
+	}
+	return filepath.Join(home, "Games", "umu", gameID)
+}
+
+// resolveUmuProtonPath resolves PROTONPATH the way umu-launcher does: a
+// Proton directory, a release name or the GE-Proton/UMU-Proton aliases for
+// the newest installed build. Unset means the newest UMU-Proton.
+func resolveUmuProtonPath() string {
+	protonPath := strings.TrimSpace(os.Getenv("PROTONPATH"))
+	if filepath.IsAbs(protonPath) {
+		return filepath.Join(protonPath, "proton")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	toolsDir := filepath.Join(home, ".local", "share", "Steam", "compatibilitytools.d")
+
+	prefix := "UMU-Proton"
+	switch protonPath {
+	case "", "UMU-Proton", "UMU-Latest":
+	case "GE-Proton", "GE-Latest":
+		prefix = "GE-Proton"
+	default:
+		candidate := filepath.Join(toolsDir, protonPath, "proton")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		return ""
+	}
+
+	entries, err := os.ReadDir(toolsDir)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	return filepath.Join(toolsDir, names[len(names)-1], "proton")
+}
+
+// naturalLess compares strings treating digit runs as numbers, so
+// GE-Proton9-9 sorts before GE-Proton9-20.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
@@ -0,0 +1,104 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/winereg"
+)
+
+// dotnet48Release is the minimum NDP\v4\Full Release value of .NET Framework 4.8.
+const dotnet48Release = 528040
+
+// verbDetectors check whether a winetricks verb is present in a prefix by
+// inspecting the prefix directly, which is faster than winetricks
+// list-installed and also works for prefixes prepared by other tools.
+var verbDetectors = map[string]func(prefixPath string) (bool, error){
+	"dotnet48":  detectDotnet48,
+	"corefonts": detectCorefonts,
+}
+
+// detectInstalledVerbs runs the native detectors for verbs. It returns the
+// detection results and the verbs that have no detector or whose detector
+// failed, which callers can check another way.
+func detectInstalledVerbs(logger *logging.Logger, prefixPath string, verbs []string) (map[string]bool, []string) {
+	logger = logger.WithComponent("launch.detect")
+	installed := make(map[string]bool, len(verbs))
+	var undetected []string
+	for _, verb := range verbs {
+		detect, ok := verbDetectors[verb]
+		if !ok {
+			undetected = append(undetected, verb)
+			continue
+		}
+		found, err := detect(prefixPath)
+		if err != nil {
+			logger.Warn("detecting %s in %s failed: %v", verb, prefixPath, err)
+			undetected = append(undetected, verb)
+			continue
+		}
+		logger.Debug("detected %s in prefix: %t", verb, found)
+		installed[verb] = found
+	}
+	return installed, undetected
+}
+
+// detectDotnet48 checks the .NET Framework setup registry key and the CLR
+// itself. Wine Mono registers neither, so it is not mistaken for .NET.
+func detectDotnet48(prefixPath string) (bool, error) {
+	system, err := winereg.Load(filepath.Join(prefixPath, "system.reg"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	key := system.Key(`Software\Microsoft\NET Framework Setup\NDP\v4\Full`)
+	if key == nil {
+		return false, nil
+	}
+	value, ok := key.Value("Release")
+	if !ok {
+		return false, nil
+	}
+	release, ok := value.Uint32()
+	if !ok {
+		return false, fmt.Errorf("unexpected type %d for .NET Release value", value.Type)
+	}
+	if release < dotnet48Release {
+		return false, nil
+	}
+
+	clr := filepath.Join(prefixPath, "drive_c", "windows", "Microsoft.NET", "Framework", "v4.0.30319", "clr.dll")
+	if _, err := os.Stat(clr); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// detectCorefonts checks that all core font files exist in windows/Fonts.
+func detectCorefonts(prefixPath string) (bool, error) {
+	entries, err := os.ReadDir(filepath.Join(prefixPath, "drive_c", "windows", "Fonts"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[strings.ToLower(entry.Name())] = true
+	}
+	for _, font := range corefontsFiles {
+		if !present[font] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
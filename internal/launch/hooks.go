@@ -0,0 +1,36 @@
+package launch
+
+import (
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/hooks"
+)
+
+// hookEnv returns the hook environment describing a launch target. PIDs and
+// the game exit code are added by Run once they are known.
+func hookEnv(target launchTarget) map[string]string {
+	env := map[string]string{
+		hooks.EnvGameID: target.GameID,
+		hooks.EnvSource: target.Source,
+		hooks.EnvPrefix: target.Prefix,
+		hooks.EnvProton: target.ProtonPath,
+		hooks.EnvWine:   target.Wine,
+	}
+	if env[hooks.EnvWine] == "" {
+		env[hooks.EnvWine] = "wine"
+	}
+	for key, value := range protonEnv(target) {
+		env[key] = value
+	}
+	return env
+}
+
+// protonEnv returns the version and flavor of the target's Proton build for
+// hooks and WeMod; it is empty for plain Wine targets.
+func protonEnv(target launchTarget) map[string]string {
+	if target.Proton == nil {
+		return nil
+	}
+	return map[string]string{
+		hooks.EnvProtonVersion: target.Proton.Version,
+		hooks.EnvProtonFlavor:  target.Proton.Flavor,
+	}
+}
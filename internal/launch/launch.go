@@ -8,23 +8,52 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/hooks"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/prefixlock"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/proton"
 	process "github.com/NichSchlagen/wemod-proton-launcher-go/internal/runtime"
 )
 
-const runtimeReadyMarker = ".wemod_launcher_runtime_ready"
-
 const wemodNoGameStabilityWindow = 10 * time.Second
 const wemodWithGameStabilityWindow = 8 * time.Second
 
 type wemodRuntime struct {
-	cmd *exec.Cmd
+	cmd     *exec.Cmd
+	profile string
+	// done is closed once the process was reaped; err is its exit status.
+	done chan struct{}
+	err  error
+}
+
+func newWeModRuntime(cmd *exec.Cmd, profile string) *wemodRuntime {
+	w := &wemodRuntime{cmd: cmd, profile: profile, done: make(chan struct{})}
+	go func() {
+		w.err = cmd.Wait()
+		close(w.done)
+	}()
+	return w
+}
+
+func (w *wemodRuntime) pid() int {
+	return w.cmd.Process.Pid
+}
+
+func (w *wemodRuntime) exited() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
 }
 
 func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args []string) error {
@@ -32,7 +61,12 @@ func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args [
 	logger.Info("launch workflow started")
 	logger.Debug("launch args: %q", args)
 
-	gameCmd, parseInfo, err := parseGameCommandArgs(args)
+	options, commandArgs, err := splitLaunchOptions(args)
+	if err != nil {
+		logger.Error("failed parsing launch options: %v", err)
+		return err
+	}
+	gameCmd, parseInfo, err := parseGameCommandArgs(commandArgs)
 	if err != nil {
 		logger.Error("failed parsing game command args: %v", err)
 		return err
@@ -57,14 +91,33 @@ func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args [
 		return fmt.Errorf("WeMod executable missing at %s", cfg.Paths.WeModExePath)
 	}
 
-	wemodPrefix, protonMode := resolveWeModPrefix(cfg, gameCmd)
-	logSteamProtonContext(logger, gameCmd, protonMode, wemodPrefix)
-	env := buildWeModEnv(logger, wemodPrefix, gameCmd, protonMode)
-	logger.Info("using WeMod prefix: %s", wemodPrefix)
+	target, err := resolveLaunchTarget(cfg, options, gameCmd)
+	if err != nil {
+		logger.Error("failed resolving launch target: %v", err)
+		return err
+	}
+	wemodPrefix := target.Prefix
+	protonMode := target.GamePrefix
+	logger = logger.With("game_id", target.GameID, "source", target.Source, "prefix", target.Prefix)
+	logSteamProtonContext(logger, gameCmd, target)
+	env := buildWeModEnv(logger, target)
+	logger.Info("using WeMod prefix: %s (source=%s)", wemodPrefix, target.Source)
 
 	if protonMode {
-		userNotice("Checking game prefix dependencies (corefonts/dotnet48) ...")
-		if err := ensurePrefixRuntime(ctx, logger, wemodPrefix, gameCmd, protonMode); err != nil {
+		locks, err := lockGamePrefix(ctx, cfg, logger, wemodPrefix, "launch")
+		if err != nil {
+			logger.Error("game prefix is locked: %v", err)
+			return err
+		}
+		gameID := target.GameID
+		prepOptions := runtimePrepOptions{
+			Verbs:     cfg.RuntimeVerbs(gameID),
+			Strategy:  cfg.RuntimeInstallStrategy(gameID),
+			OwnPrefix: cfg.Paths.PrefixDir,
+		}
+		logger.Debug("game id=%q required runtime verbs: %v (strategy=%s)", gameID, prepOptions.Verbs, prepOptions.Strategy)
+		userNotice("Checking game prefix dependencies (%s) ...", strings.Join(prepOptions.Verbs, "/"))
+		if err := ensurePrefixRuntime(ctx, logger, target, prepOptions); err != nil {
 			logger.Warn("game prefix runtime prep failed, continuing anyway: %v", err)
 			userNotice("Prefix preparation failed, starting WeMod anyway ...")
 		} else {
@@ -72,44 +125,57 @@ func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args [
 		}
 
 		// Sync WeMod login + config from own prefix into game prefix
-		if err := syncWeModData(logger, cfg.Paths.PrefixDir, wemodPrefix); err != nil {
+		if err := syncWeModData(logger, cfg.Paths.PrefixDir, target); err != nil {
 			logger.Warn("sync WeMod data to game prefix failed: %v", err)
 		}
+		locks.release(logger)
+	} else {
+		// WeMod runs in the own prefix; keep reset/prefix download from
+		// replacing it underneath.
+		ownLock, err := prefixlock.Own(ctx, cfg, "launch", true)
+		if err != nil {
+			logger.Error("own prefix is locked: %v", err)
+			return err
+		}
+		defer ownLock.Release()
+	}
+
+	hookCfg := cfg.GameHooks(target.GameID)
+	hookVars := hookEnv(target)
+	if err := hooks.Run(ctx, logger, hooks.PreLaunch, hookCfg, hookVars); err != nil {
+		return fmt.Errorf("launch aborted: %w", err)
+	}
+
+	compatResult := newCompatResult(cfg, target)
+	skipWeMod, err := checkCompatibility(cfg, logger, compatResult, len(gameCmd) == 0)
+	if err != nil {
+		return err
 	}
 
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+	defer signal.Stop(signals)
+
 	if len(gameCmd) == 0 {
 		logger.Info("no game command provided; starting standalone WeMod mode")
-		wemodProc, err := startWeModProcessWithRecovery(ctx, cfg, logger, gameCmd, env, protonMode)
+		session := newLaunchSession(cfg, logger, target, env, compatResult, nil, wemodNoGameStabilityWindow)
+		defer session.closeWeModLog()
+		defer session.register()()
+		closeControl := session.serveControl(ctx)
+		defer closeControl()
+		wemodProc, err := session.startWeMod(ctx)
 		if err != nil {
 			return fmt.Errorf("start wemod: %w", err)
 		}
-		if err := ensureProcessRunning(wemodProc.cmd.Process.Pid, wemodNoGameStabilityWindow); err != nil {
-			return fmt.Errorf("start wemod: %w", err)
-		}
-		logger.Info("WeMod started (pid=%d)", wemodProc.cmd.Process.Pid)
+		hookVars[hooks.EnvWeModPID] = strconv.Itoa(wemodProc.pid())
+		_ = hooks.Run(ctx, logger, hooks.PostWeModStart, hookCfg, hookVars)
 		logger.Info("no game command provided, waiting until WeMod exits")
 
-		waitDone := make(chan error, 1)
-		go func() {
-			waitDone <- wemodProc.cmd.Wait()
-		}()
-
-		select {
-		case err := <-waitDone:
-			if err != nil {
+		if err := session.waitWeMod(ctx); err != nil {
+			if ctx.Err() == nil {
 				return fmt.Errorf("wemod exited with error: %w", err)
 			}
-		case <-ctx.Done():
-			logger.Info("interrupt received, stopping WeMod and launcher")
-			if stopErr := stopWeModProcessGroup(wemodProc.cmd.Process.Pid); stopErr != nil {
-				logger.Warn("failed to stop WeMod process group: %v", stopErr)
-			}
-
-			select {
-			case <-waitDone:
-			case <-time.After(3 * time.Second):
-				logger.Warn("timeout waiting for WeMod process to exit after interrupt")
-			}
+			session.shutdown(receivedSignal(signals), nil, nil)
 			return nil
 		}
 
@@ -123,43 +189,84 @@ func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args [
 	}
 
 	logger.Info("starting game command: %s", strings.Join(gameCmd, " "))
-	gameProc, err := process.Start(ctx, logger, gameCmd[0], gameCmd[1:], nil)
+	// The game is not bound to ctx: on a termination signal it gets the signal
+	// forwarded and a grace period instead of being killed right away.
+	gameProc, err := process.Start(context.WithoutCancel(ctx), logger, gameCmd[0], gameCmd[1:], buildGameEnv(target))
 	if err != nil {
 		return fmt.Errorf("start game: %w", err)
 	}
 
+	hookVars[hooks.EnvGamePID] = strconv.Itoa(gameProc.Process.Pid)
+	session := newLaunchSession(cfg, logger, target, env, compatResult, gameCmd, wemodWithGameStabilityWindow)
+	defer session.closeWeModLog()
+	session.setGamePID(gameProc.Process.Pid)
+
+	// WeMod startup (including the profile fallback chain) is abandoned once
+	// the game exits.
+	gameExited := make(chan struct{})
+	var gameErr error
+	go func() {
+		gameErr = gameProc.Wait()
+		close(gameExited)
+	}()
+	startCtx, cancelStart := context.WithCancel(ctx)
+	defer cancelStart()
+	go func() {
+		select {
+		case <-gameExited:
+			cancelStart()
+		case <-startCtx.Done():
+		}
+	}()
+
+	defer session.register()()
+	closeControl := session.serveControl(startCtx)
+	defer closeControl()
+	if cfg.WeMod.Watchdog.Enabled {
+		go session.watchWeMod(startCtx, cfg.WeMod.Watchdog)
+	}
+
 	var wemodProc *wemodRuntime
-	if protonMode {
+	switch {
+	case skipWeMod:
+		logger.Warn("WeMod start skipped by compatibility check")
+	case protonMode:
 		logger.Info("proton mode: delaying WeMod start to avoid blocking game launch")
 		time.Sleep(2 * time.Second)
-		wemodProc, err = startWeModProcessWithRecovery(ctx, cfg, logger, gameCmd, env, protonMode)
+		wemodProc, err = session.startWeMod(startCtx)
 		if err != nil {
 			logger.Warn("failed to start WeMod after game launch: %v", err)
-		} else {
-			if runErr := ensureProcessRunning(wemodProc.cmd.Process.Pid, wemodWithGameStabilityWindow); runErr != nil {
-				logger.Warn("WeMod exited shortly after start: %v", runErr)
-			} else {
-				logger.Info("WeMod started (pid=%d)", wemodProc.cmd.Process.Pid)
-			}
 		}
-	} else {
-		wemodProc, err = startWeModProcessWithRecovery(ctx, cfg, logger, gameCmd, env, protonMode)
+	default:
+		wemodProc, err = session.startWeMod(startCtx)
 		if err != nil {
 			_ = gameProc.Process.Kill()
+			<-gameExited
 			return fmt.Errorf("start wemod: %w", err)
 		}
-		if err := ensureProcessRunning(wemodProc.cmd.Process.Pid, wemodWithGameStabilityWindow); err != nil {
-			_ = gameProc.Process.Kill()
-			return fmt.Errorf("start wemod: %w", err)
-		}
-		logger.Info("WeMod started (pid=%d)", wemodProc.cmd.Process.Pid)
+	}
+	if wemodProc != nil {
+		hookVars[hooks.EnvWeModPID] = strconv.Itoa(wemodProc.pid())
+		_ = hooks.Run(ctx, logger, hooks.PostWeModStart, hookCfg, hookVars)
 	}
 
-	if err := gameProc.Wait(); err != nil {
+	select {
+	case <-gameExited:
+	case sig := <-signals:
+		session.shutdown(sig, gameProc, gameExited)
+	}
+	closeControl()
+	if err := gameErr; err != nil {
 		logger.Warn("game process exited with error: %v", err)
 	} else {
 		logger.Info("game process finished")
 	}
+	hookVars[hooks.EnvGameExitCode] = strconv.Itoa(gameProc.ProcessState.ExitCode())
+	if current := session.currentWeMod(); current != nil {
+		hookVars[hooks.EnvWeModPID] = strconv.Itoa(current.pid())
+	}
+	// Save backups and similar cleanup also run when the launch was interrupted.
+	_ = hooks.Run(context.WithoutCancel(ctx), logger, hooks.PostGameExit, hookCfg, hookVars)
 	logger.Info("launch workflow completed")
 
 	return nil
@@ -172,19 +279,34 @@ func Sync(ctx context.Context, cfg *config.Config, logger *logging.Logger, args
 	logger.Info("sync workflow started")
 	logger.Debug("sync args: %q", args)
 
-	gameCmd, _, err := parseGameCommandArgs(args)
+	options, commandArgs, err := splitLaunchOptions(args)
+	if err != nil {
+		logger.Error("failed parsing sync options: %v", err)
+		return err
+	}
+	gameCmd, _, err := parseGameCommandArgs(commandArgs)
 	if err != nil {
 		logger.Error("failed parsing sync command args: %v", err)
 		return err
 	}
 
-	targetPrefix, protonMode := resolveWeModPrefix(cfg, gameCmd)
-	if !protonMode {
-		logger.Error("sync requested without proton context")
-		return errors.New("sync requires a Proton prefix (pass %command% or set STEAM_COMPAT_DATA_PATH/WINEPREFIX)")
+	target, err := resolveLaunchTarget(cfg, options, gameCmd)
+	if err != nil {
+		logger.Error("failed resolving sync target: %v", err)
+		return err
+	}
+	if !target.GamePrefix {
+		logger.Error("sync requested without game prefix context")
+		return errors.New("sync requires a game prefix (pass %command%, --lutris/--heroic/--bottles or set STEAM_COMPAT_DATA_PATH/WINEPREFIX)")
 	}
 
-	if err := syncWeModData(logger, cfg.Paths.PrefixDir, targetPrefix); err != nil {
+	locks, err := lockGamePrefix(ctx, cfg, logger, target.Prefix, "sync")
+	if err != nil {
+		logger.Error("sync target is locked: %v", err)
+		return err
+	}
+	defer locks.release(logger)
+	if err := syncWeModData(logger, cfg.Paths.PrefixDir, target); err != nil {
 		logger.Error("sync workflow failed: %v", err)
 		return err
 	}
@@ -193,13 +315,19 @@ func Sync(ctx context.Context, cfg *config.Config, logger *logging.Logger, args
 }
 
 // ResetOwnPrefix removes and recreates the dedicated own WeMod prefix.
-func ResetOwnPrefix(cfg *config.Config, logger *logging.Logger) error {
+func ResetOwnPrefix(ctx context.Context, cfg *config.Config, logger *logging.Logger) error {
 	logger = logger.WithComponent("launch.reset")
 	prefixDir := strings.TrimSpace(cfg.Paths.PrefixDir)
 	if prefixDir == "" {
 		logger.Error("reset failed: empty paths.prefix_dir")
 		return errors.New("paths.prefix_dir is empty")
 	}
+	lock, err := prefixlock.Own(ctx, cfg, "reset", false)
+	if err != nil {
+		logger.Error("reset failed: %v", err)
+		return err
+	}
+	defer lock.Release()
 
 	logger.Warn("resetting own WeMod prefix at %s", prefixDir)
 	if err := os.RemoveAll(prefixDir); err != nil {
@@ -233,9 +361,15 @@ func parseGameCommandArgs(args []string) ([]string, string, error) {
 		return nil, "", nil
 	}
 
-	verbIndex := protonVerbIndex(normalized)
-	if verbIndex > 0 && strings.Contains(strings.ToLower(filepath.Base(normalized[verbIndex-1])), "proton") {
-		return normalized[verbIndex-1:], "detected Proton launch command", nil
+	// Wrappers in front of Proton (Steam Linux Runtime, reaper, gamemoderun)
+	// are kept so the game runs exactly as Steam would start it.
+	switch inspectGameCommand(normalized).kind {
+	case commandProton:
+		return normalized, "detected Proton launch command", nil
+	case commandSteamRuntime:
+		return normalized, "detected Proton launch command via Steam Linux Runtime", nil
+	case commandUmu:
+		return normalized, "detected umu-launcher command", nil
 	}
 
 	if looksLikeWindowsExecutable(normalized[0]) {
@@ -267,44 +401,56 @@ func validateCommand(command string) error {
 	return nil
 }
 
-func startWeModProcess(ctx context.Context, cfg *config.Config, logger *logging.Logger, gameCmd []string, env map[string]string, protonMode bool) (*wemodRuntime, error) {
-	if protonMode {
-		if protonWine, ok := env["WINE"]; ok && protonWine != "" {
-			logger.Info("starting WeMod with Proton wine binary: %s", protonWine)
-			cmd, err := process.StartDetached(ctx, logger, protonWine, []string{cfg.Paths.WeModExePath}, env)
-			if err != nil {
-				return nil, err
-			}
-			return &wemodRuntime{cmd: cmd}, nil
-		}
-		if len(gameCmd) > 0 {
-			if protonWine := resolveProtonWineBinary(gameCmd[0]); protonWine != "" {
-				logger.Info("starting WeMod with Proton wine binary: %s", protonWine)
-				cmd, err := process.StartDetached(ctx, logger, protonWine, []string{cfg.Paths.WeModExePath}, env)
-				if err != nil {
-					return nil, err
-				}
-				return &wemodRuntime{cmd: cmd}, nil
+func startWeModProcess(ctx context.Context, cfg *config.Config, logger *logging.Logger, env map[string]string, profile weModProfile, output *os.File) (*wemodRuntime, error) {
+	wine := "wine"
+	if targetWine := env["WINE"]; targetWine != "" {
+		wine = targetWine
+		logger.Info("starting WeMod with wine binary: %s (profile=%s)", wine, profile.Name)
+	} else {
+		logger.Info("starting WeMod directly with system wine in %s (profile=%s)", env["WINEPREFIX"], profile.Name)
+	}
+
+	args := append([]string{cfg.Paths.WeModExePath}, profile.Args...)
+	// An inherited WINEDEBUG (e.g. from Steam launch options) wins as well.
+	if wineDebug := cfg.WeMod.Log.WineDebug; wineDebug != "" && os.Getenv("WINEDEBUG") == "" && env["WINEDEBUG"] == "" && profile.Env["WINEDEBUG"] == "" {
+		withDebug := make(map[string]string, len(env)+1)
+		for key, value := range env {
+			withDebug[key] = value
+		}
+		withDebug["WINEDEBUG"] = wineDebug
+		env = withDebug
+	}
+	if len(profile.Env) > 0 {
+		merged := make(map[string]string, len(env)+len(profile.Env))
+		for key, value := range env {
+			merged[key] = value
+		}
+		applied := make(map[string]string, len(profile.Env))
+		for key, value := range profile.Env {
+			// The prefix is owned by the launch target.
+			if key == "WINEPREFIX" {
+				logger.Warn("ignoring WINEPREFIX from WeMod env config; use --prefix or [games.<id>] prefix")
+				continue
 			}
+			merged[key] = value
+			applied[key] = value
 		}
-		logger.Info("starting WeMod with system wine in Proton prefix")
-		cmd, err := process.StartDetached(ctx, logger, "wine", []string{cfg.Paths.WeModExePath}, env)
-		if err != nil {
-			return nil, err
-		}
-		return &wemodRuntime{cmd: cmd}, nil
+		env = merged
+		logger.Info("WeMod env overrides: %s", logging.FormatEnv(applied))
 	}
-
-	logger.Info("starting WeMod directly with wine")
-	cmd, err := process.StartDetached(ctx, logger, "wine", []string{cfg.Paths.WeModExePath}, env)
+	if len(profile.Args) > 0 {
+		logger.Info("WeMod args: %q", logging.RedactArgs(profile.Args))
+	}
+	writeWeModLogHeader(output, logger.SessionID(), profile.Name, profile.Args, env)
+	cmd, err := process.StartDetached(ctx, logger, wine, args, env, output)
 	if err != nil {
 		return nil, err
 	}
-	return &wemodRuntime{cmd: cmd}, nil
+	return newWeModRuntime(cmd, profile.Name), nil
 }
 
-func startWeModProcessWithRecovery(ctx context.Context, cfg *config.Config, logger *logging.Logger, gameCmd []string, env map[string]string, protonMode bool) (*wemodRuntime, error) {
-	wemodProc, err := startWeModProcess(ctx, cfg, logger, gameCmd, env, protonMode)
+func startWeModProcessWithRecovery(ctx context.Context, cfg *config.Config, logger *logging.Logger, env map[string]string, profile weModProfile, output *os.File) (*wemodRuntime, error) {
+	wemodProc, err := startWeModProcess(ctx, cfg, logger, env, profile, output)
 	if err == nil {
 		return wemodProc, nil
 	}
@@ -312,14 +458,14 @@ func startWeModProcessWithRecovery(ctx context.Context, cfg *config.Config, logg
 	logger.Warn("initial WeMod start failed: %v", err)
 	userNotice("WeMod start failed, initializing Wine and retrying ...")
 
-	winebootBinary := resolveWineBootBinary(gameCmd, protonMode)
+	winebootBinary := resolveWineBootBinary(env["WINE"])
 	if bootErr := process.Run(ctx, logger, winebootBinary, []string{"-u"}, env); bootErr != nil {
 		logger.Warn("wineboot recovery failed (%s): %v", winebootBinary, bootErr)
 	}
 
 	time.Sleep(2 * time.Second)
 
-	wemodProc, retryErr := startWeModProcess(ctx, cfg, logger, gameCmd, env, protonMode)
+	wemodProc, retryErr := startWeModProcess(ctx, cfg, logger, env, profile, output)
 	if retryErr == nil {
 		userNotice("WeMod started successfully on retry.")
 		return wemodProc, nil
@@ -328,20 +474,6 @@ func startWeModProcessWithRecovery(ctx context.Context, cfg *config.Config, logg
 	return nil, fmt.Errorf("initial error: %w; retry error: %w", err, retryErr)
 }
 
-func resolveProtonWineBinary(protonPath string) string {
-	protonDir := filepath.Dir(protonPath)
-	candidates := []string{
-		filepath.Join(protonDir, "files", "bin", "wine"),
-		filepath.Join(protonDir, "files", "bin", "wine64"),
-	}
-	for _, candidate := range candidates {
-		if st, err := os.Stat(candidate); err == nil && st.Mode()&0o111 != 0 {
-			return candidate
-		}
-	}
-	return ""
-}
-
 func resolveProtonWineServerBinary(protonWineBinary string) string {
 	candidate := filepath.Join(filepath.Dir(protonWineBinary), "wineserver")
 	if st, err := os.Stat(candidate); err == nil && st.Mode()&0o111 != 0 {
@@ -350,19 +482,24 @@ func resolveProtonWineServerBinary(protonWineBinary string) string {
 	return "wineserver"
 }
 
-func resolveWineBootBinary(gameCmd []string, protonMode bool) string {
-	if protonMode && len(gameCmd) > 0 {
-		if protonWine := resolveProtonWineBinary(gameCmd[0]); protonWine != "" {
-			candidate := filepath.Join(filepath.Dir(protonWine), "wineboot")
-			if st, err := os.Stat(candidate); err == nil && st.Mode()&0o111 != 0 {
-				return candidate
-			}
+// resolveWineBootBinary returns the wineboot next to the given wine binary,
+// falling back to the system wineboot.
+func resolveWineBootBinary(wineBinary string) string {
+	if wineBinary != "" {
+		candidate := filepath.Join(filepath.Dir(wineBinary), "wineboot")
+		if st, err := os.Stat(candidate); err == nil && st.Mode()&0o111 != 0 {
+			return candidate
 		}
 	}
 	return "wineboot"
 }
 
 func resolveWeModPrefix(cfg *config.Config, gameCmd []string) (string, bool) {
+	if inspectGameCommand(gameCmd).kind == commandUmu {
+		if prefix := resolveUmuPrefix(); prefix != "" {
+			return prefix, true
+		}
+	}
 	if isProtonCommand(gameCmd) {
 		if w := strings.TrimSpace(os.Getenv("WINEPREFIX")); w != "" {
 			return w, true
@@ -374,50 +511,107 @@ func resolveWeModPrefix(cfg *config.Config, gameCmd []string) (string, bool) {
 	return cfg.Paths.PrefixDir, false
 }
 
-func isProtonCommand(gameCmd []string) bool {
-	verbIndex := protonVerbIndex(gameCmd)
-	if verbIndex <= 0 {
-		return false
+// resolveGameID returns the Steam AppID of the running game, if known. It is
+// used to look up per-game config overrides.
+func resolveGameID() string {
+	for _, key := range []string{"SteamAppId", "SteamGameId", "STEAM_COMPAT_APP_ID", "GAMEID"} {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" && value != "0" {
+			return value
+		}
 	}
-	base := strings.ToLower(filepath.Base(gameCmd[verbIndex-1]))
-	return strings.Contains(base, "proton")
+	if compat := strings.TrimSpace(os.Getenv("STEAM_COMPAT_DATA_PATH")); compat != "" {
+		return filepath.Base(filepath.Clean(compat))
+	}
+	return ""
 }
 
-func ensurePrefixRuntime(ctx context.Context, logger *logging.Logger, prefixPath string, gameCmd []string, protonMode bool) error {
+// runtimePrepOptions controls how ensurePrefixRuntime prepares a game prefix.
+type runtimePrepOptions struct {
+	Verbs     []string
+	Strategy  string
+	OwnPrefix string
+}
+
+func ensurePrefixRuntime(ctx context.Context, logger *logging.Logger, target launchTarget, options runtimePrepOptions) error {
 	logger = logger.WithComponent("launch.prefix-runtime")
-	markerPath := filepath.Join(prefixPath, runtimeReadyMarker)
-	if _, err := os.Stat(markerPath); err == nil {
-		logger.Debug("runtime marker found in game prefix, skipping winetricks checks")
+	prefixPath := target.Prefix
+	verbs := options.Verbs
+	markerPath := RuntimeMarkerPath(prefixPath)
+	marker := currentRuntimeState(target)
+	existing, err := readRuntimeMarker(markerPath)
+	if err != nil {
+		logger.Warn("could not read runtime marker, re-checking all verbs: %v", err)
+	}
+	if existing != nil {
+		if reasons := existing.staleReasons(marker); len(reasons) > 0 {
+			logger.Info("runtime marker is stale (%s), revalidating prefix", strings.Join(reasons, "; "))
+			marker.CreatedAt = existing.CreatedAt
+		} else {
+			marker = *existing
+		}
+	}
+
+	missing := marker.missingVerbs(verbs)
+	if len(missing) == 0 {
+		logger.Debug("runtime marker covers required verbs %v, skipping winetricks checks", verbs)
 		userNotice("Game prefix already prepared, skipping installation.")
 		return nil
 	}
+	if len(marker.Verbs) > 0 {
+		logger.Info("runtime marker is missing verbs %v, checking only those", missing)
+	}
 
-	env := buildPrefixRuntimeEnv(prefixPath, gameCmd, protonMode)
+	env := buildPrefixRuntimeEnv(target)
 	userNotice("Checking installed components in game prefix ...")
-	var installed map[string]bool
-	err := withProgressDialog(
-		ctx,
-		"WeMod Launcher",
-		"Checking installed components in game prefix ...",
-		func() error {
-			var listErr error
-			installed, listErr = listInstalledVerbs(ctx, env)
-			return listErr
-		},
-	)
-	if err != nil {
-		logger.Warn("could not inspect installed winetricks verbs in game prefix, continuing with best-effort install: %v", err)
-		userNotice("Could not verify installed components, attempting best-effort installation ...")
-		installed = map[string]bool{}
+	installed, undetected := detectInstalledVerbs(logger, prefixPath, missing)
+	if len(undetected) > 0 {
+		logger.Debug("no native detection for verbs %v, asking winetricks", undetected)
+		var listed map[string]bool
+		err = withProgressDialog(
+			ctx,
+			"WeMod Launcher",
+			"Checking installed components in game prefix ...",
+			func() error {
+				var listErr error
+				listed, listErr = listInstalledVerbs(ctx, env)
+				return listErr
+			},
+		)
+		if err != nil {
+			logger.Warn("could not inspect installed winetricks verbs in game prefix, continuing with best-effort install: %v", err)
+			userNotice("Could not verify installed components, attempting best-effort installation ...")
+		}
+		for _, verb := range undetected {
+			installed[verb] = listed[verb]
+		}
 	}
 	userNotice("Dependency check completed.")
 
-	required := []string{"corefonts", "dotnet48"}
-	for _, verb := range required {
+	for _, verb := range missing {
 		if installed[verb] {
 			userNotice("Already installed: %s", verb)
+			marker.Verbs[verb] = time.Now().UTC()
 			continue
 		}
+		if options.Strategy == config.InstallStrategyClone && canCloneVerb(verb) {
+			logger.Info("cloning %s from own prefix %s into game prefix", verb, options.OwnPrefix)
+			userNotice("Copying %s from own WeMod prefix ...", verb)
+			cloneErr := withProgressDialog(
+				ctx,
+				"WeMod Launcher",
+				fmt.Sprintf("Copying %s into game prefix ...", verb),
+				func() error {
+					return cloneVerbFromPrefix(logger, options.OwnPrefix, prefixPath, verb)
+				},
+			)
+			if cloneErr == nil {
+				marker.Verbs[verb] = time.Now().UTC()
+				userNotice("Installed: %s", verb)
+				continue
+			}
+			logger.Warn("cloning %s from own prefix failed, falling back to winetricks: %v", verb, cloneErr)
+			userNotice("Copying %s failed, installing with winetricks ...", verb)
+		}
 		logger.Info("installing %s into game prefix for WeMod/game integration", verb)
 		userNotice("Installing into game prefix: %s", verb)
 		if err := withProgressDialog(
@@ -428,12 +622,16 @@ func ensurePrefixRuntime(ctx context.Context, logger *logging.Logger, prefixPath
 				return process.Run(ctx, logger, "winetricks", []string{"-q", verb}, env)
 			},
 		); err != nil {
+			if writeErr := writeRuntimeMarker(markerPath, &marker); writeErr != nil {
+				logger.Warn("failed to write runtime marker: %v", writeErr)
+			}
 			return fmt.Errorf("install %s: %w", verb, err)
 		}
+		marker.Verbs[verb] = time.Now().UTC()
 		userNotice("Installed: %s", verb)
 	}
 
-	if err := os.WriteFile(markerPath, []byte("ok\n"), 0o644); err != nil {
+	if err := writeRuntimeMarker(markerPath, &marker); err != nil {
 		logger.Warn("failed to write runtime marker: %v", err)
 	}
 
@@ -441,8 +639,8 @@ func ensurePrefixRuntime(ctx context.Context, logger *logging.Logger, prefixPath
 }
 
 // syncWeModData copies the WeMod AppData folder (login + settings) from the
-// own WeMod prefix into the game's Proton prefix, so the user stays logged in.
-func syncWeModData(logger *logging.Logger, ownPrefixDir, gamePrefixDir string) error {
+// own WeMod prefix into the game prefix, so the user stays logged in.
+func syncWeModData(logger *logging.Logger, ownPrefixDir string, target launchTarget) error {
 	logger = logger.WithComponent("launch.sync-data")
 	src := findWeModAppDataDir(ownPrefixDir)
 	if src == "" {
@@ -450,9 +648,9 @@ func syncWeModData(logger *logging.Logger, ownPrefixDir, gamePrefixDir string) e
 		return errors.New("no WeMod data found in own prefix (start WeMod once in no-game mode first)")
 	}
 
-	dst := findOrCreateWeModAppDataDir(gamePrefixDir)
+	dst := findOrCreateWeModAppDataDir(target.Prefix, target.ProtonPath != "")
 	if dst == "" {
-		logger.Error("sync target could not be resolved in game prefix: %s", gamePrefixDir)
+		logger.Error("sync target could not be resolved in game prefix: %s", target.Prefix)
 		return errors.New("could not resolve WeMod AppData target in game prefix")
 	}
 
@@ -483,32 +681,37 @@ func findWeModAppDataDir(prefixDir string) string {
 	return ""
 }
 
-// findOrCreateWeModAppDataDir finds or creates the WeMod AppData dir in the target prefix.
-// Prefers "steamuser" (Proton default), falls back to first existing user, or creates steamuser.
-func findOrCreateWeModAppDataDir(prefixDir string) string {
+// findOrCreateWeModAppDataDir finds or creates the WeMod AppData dir in the
+// target prefix. It uses the Windows user that already exists in the prefix;
+// new prefixes get "steamuser" under Proton and $USER under plain Wine.
+func findOrCreateWeModAppDataDir(prefixDir string, proton bool) string {
 	usersDir := filepath.Join(prefixDir, "drive_c", "users")
 
-	// Prefer steamuser (Proton default)
-	steamuser := filepath.Join(usersDir, "steamuser", "AppData", "Roaming", "WeMod")
-	if err := os.MkdirAll(steamuser, 0o755); err == nil {
-		return steamuser
+	user := ""
+	if entries, err := os.ReadDir(usersDir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() && e.Name() != "Public" {
+				user = e.Name()
+				break
+			}
+		}
 	}
-
-	// Fall back to first existing user
-	entries, err := os.ReadDir(usersDir)
-	if err != nil {
+	if user == "" {
+		if proton {
+			user = "steamuser"
+		} else {
+			user = os.Getenv("USER")
+		}
+	}
+	if user == "" {
 		return ""
 	}
-	for _, e := range entries {
-		if !e.IsDir() {
-			continue
-		}
-		candidate := filepath.Join(usersDir, e.Name(), "AppData", "Roaming", "WeMod")
-		if err := os.MkdirAll(candidate, 0o755); err == nil {
-			return candidate
-		}
+
+	dst := filepath.Join(usersDir, user, "AppData", "Roaming", "WeMod")
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return ""
 	}
-	return ""
+	return dst
 }
 
 // copyDir recursively copies src into dst, overwriting existing files.
@@ -596,28 +799,38 @@ func withProgressDialog(ctx context.Context, title string, text string, run func
 	return runErr
 }
 
-func buildPrefixRuntimeEnv(prefixPath string, gameCmd []string, protonMode bool) map[string]string {
-	env := map[string]string{"WINEPREFIX": prefixPath}
-	if protonMode && len(gameCmd) > 0 {
-		if protonWine := resolveProtonWineBinary(gameCmd[0]); protonWine != "" {
-			env["WINE"] = protonWine
-			env["WINESERVER"] = resolveProtonWineServerBinary(protonWine)
-		}
+// buildGameEnv returns the env overrides for the game command. Only explicit
+// --prefix/--wine targets need them; other launchers set up the game env.
+func buildGameEnv(target launchTarget) map[string]string {
+	if target.Source != sourceCustom {
+		return nil
+	}
+	return buildPrefixRuntimeEnv(target)
+}
+
+func buildPrefixRuntimeEnv(target launchTarget) map[string]string {
+	env := map[string]string{"WINEPREFIX": target.Prefix}
+	if target.Wine != "" {
+		env["WINE"] = target.Wine
+		env["WINESERVER"] = resolveProtonWineServerBinary(target.Wine)
 	}
 	return env
 }
 
-func buildWeModEnv(logger *logging.Logger, prefixPath string, gameCmd []string, protonMode bool) map[string]string {
+func buildWeModEnv(logger *logging.Logger, target launchTarget) map[string]string {
 	logger = logger.WithComponent("launch.env")
-	env := map[string]string{"WINEPREFIX": prefixPath}
-	if !protonMode || len(gameCmd) == 0 {
-		logger.Debug("build env without proton overrides")
+	env := map[string]string{"WINEPREFIX": target.Prefix}
+	if !target.GamePrefix {
+		logger.Debug("build env without game prefix overrides")
 		return env
 	}
 
-	if protonWine := resolveProtonWineBinary(gameCmd[0]); protonWine != "" {
-		env["WINE"] = protonWine
-		env["WINESERVER"] = resolveProtonWineServerBinary(protonWine)
+	if target.Wine != "" {
+		env["WINE"] = target.Wine
+		env["WINESERVER"] = resolveProtonWineServerBinary(target.Wine)
+	}
+	for key, value := range protonEnv(target) {
+		env[key] = value
 	}
 
 	if strings.TrimSpace(os.Getenv("PROTON_ENABLE_WAYLAND")) == "1" {
@@ -626,32 +839,62 @@ func buildWeModEnv(logger *logging.Logger, prefixPath string, gameCmd []string,
 		env["PROTON_ENABLE_WAYLAND"] = "0"
 	}
 
-	logger.Debug("build env with keys: WINEPREFIX,WINE,WINESERVER,PROTON_ENABLE_WAYLAND(optional)")
+	logger.Debug("build env with keys: WINEPREFIX,WINE,WINESERVER,WEMOD_PROTON_VERSION,WEMOD_PROTON_FLAVOR,PROTON_ENABLE_WAYLAND(optional)")
 	return env
 }
 
-func logSteamProtonContext(logger *logging.Logger, gameCmd []string, protonMode bool, wemodPrefix string) {
+// formatBuildTime formats a Unix timestamp from a Proton version file.
+func formatBuildTime(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return formatMarkerTime(time.Unix(ts, 0))
+}
+
+func logSteamProtonContext(logger *logging.Logger, gameCmd []string, target launchTarget) {
 	logger = logger.WithComponent("launch.proton")
-	if !protonMode {
+	if !target.GamePrefix {
 		logger.Debug("proton mode not detected")
 		return
 	}
 
-	logger.Info("proton mode detected")
-	if len(gameCmd) > 0 {
-		logger.Info("proton command executable: %s", gameCmd[0])
-		if protonWine := resolveProtonWineBinary(gameCmd[0]); protonWine != "" {
-			logger.Info("resolved Proton wine binary: %s", protonWine)
+	command := inspectGameCommand(gameCmd)
+	logger.Info("game prefix mode (source=%s command=%s)", target.Source, command.kind)
+	switch {
+	case target.ProtonPath != "":
+		logger.Info("proton script: %s", target.ProtonPath)
+		if info := target.Proton; info != nil {
+			logger.Info("proton build: %s (layout=%s built=%s)", info, valueOrDash(info.Layout), formatBuildTime(info.BuildTime))
+			if info.Layout == proton.LayoutDist {
+				logger.Warn("Proton %s uses the pre-5.0 dist/ layout; WeMod is not known to work with it", info.Name)
+			}
+			if info.Flavor == proton.FlavorUnknown {
+				logger.Warn("could not identify Proton flavor of %s", info.Dir)
+			}
+		}
+		if target.Wine != "" {
+			logger.Info("resolved Proton wine binary: %s", target.Wine)
 		} else {
-			logger.Warn("could not resolve Proton wine binary from: %s", gameCmd[0])
+			logger.Warn("could not resolve Proton wine binary from: %s", target.ProtonPath)
 		}
+	case target.Wine != "":
+		logger.Info("wine binary: %s", target.Wine)
+	case target.Source == sourceSteam || target.Source == sourceUmu:
+		if len(gameCmd) > 0 {
+			logger.Warn("could not locate Proton in game command: %s", gameCmd[0])
+		}
+	default:
+		logger.Info("using system wine")
 	}
 
 	logger.Debug("env WINEPREFIX=%q", os.Getenv("WINEPREFIX"))
 	logger.Debug("env STEAM_COMPAT_DATA_PATH=%q", os.Getenv("STEAM_COMPAT_DATA_PATH"))
 	logger.Debug("env STEAM_COMPAT_CLIENT_INSTALL_PATH=%q", os.Getenv("STEAM_COMPAT_CLIENT_INSTALL_PATH"))
+	if command.kind == commandUmu {
+		logger.Debug("env PROTONPATH=%q GAMEID=%q", os.Getenv("PROTONPATH"), os.Getenv("GAMEID"))
+	}
 	logger.Debug("env PROTON_ENABLE_WAYLAND=%q", os.Getenv("PROTON_ENABLE_WAYLAND"))
-	logger.Info("effective WeMod prefix=%q", wemodPrefix)
+	logger.Info("effective WeMod prefix=%q", target.Prefix)
 }
 
 func ensureProcessRunning(pid int, settleWindow time.Duration) error {
@@ -732,23 +975,6 @@ func looksLikeWindowsExecutable(value string) bool {
 	return false
 }
 
-func indexOf(items []string, needle string) int {
-	for i, item := range items {
-		if item == needle {
-			return i
-		}
-	}
-	return -1
-}
-
-func protonVerbIndex(args []string) int {
-	waitIdx := indexOf(args, "waitforexitandrun")
-	if waitIdx >= 0 {
-		return waitIdx
-	}
-	return indexOf(args, "run")
-}
-
 func askYesNo(prompt string) (bool, error) {
 	fmt.Print(prompt)
 	reader := bufio.NewReader(os.Stdin)
@@ -1,9 +1,21 @@
 package launch
 
 import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/hooks"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/proton"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
 )
 
 func TestParseGameCommandArgs_ProtonLaunch(t *testing.T) {
@@ -77,3 +89,880 @@ func TestResolveWeModPrefix_NonProtonFallsBackToOwnPrefix(t *testing.T) {
 		t.Fatalf("unexpected prefix: %s", prefix)
 	}
 }
+
+func TestRuntimeMarker_LegacyFormatIsStale(t *testing.T) {
+	markerPath := filepath.Join(t.TempDir(), runtimeReadyMarker)
+	if err := os.WriteFile(markerPath, []byte("ok\n"), 0o644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+
+	marker, err := readRuntimeMarker(markerPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marker == nil {
+		t.Fatal("expected legacy marker to be loaded")
+	}
+	if reasons := marker.staleReasons(runtimeMarker{}); len(reasons) == 0 {
+		t.Fatal("expected legacy marker to be stale")
+	}
+}
+
+func TestRuntimeMarker_RoundTripAndInvalidation(t *testing.T) {
+	markerPath := filepath.Join(t.TempDir(), runtimeReadyMarker)
+	marker := runtimeMarker{
+		LauncherVersion: "v1",
+		ProtonPath:      "/tmp/Proton 9.0/proton",
+		ProtonVersion:   "1712345678 proton-9.0-2",
+		Verbs:           map[string]time.Time{"corefonts": time.Now(), "dotnet48": time.Now()},
+	}
+	if err := writeRuntimeMarker(markerPath, &marker); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(markerPath))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected only the marker in the prefix, got %v (%v)", entries, err)
+	}
+	if info, err := os.Stat(markerPath); err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("unexpected marker mode: %v (%v)", info, err)
+	}
+
+	loaded, err := readRuntimeMarker(markerPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if missing := loaded.missingVerbs([]string{"corefonts", "dotnet48", "vcrun2019"}); len(missing) != 1 || missing[0] != "vcrun2019" {
+		t.Fatalf("unexpected missing verbs: %v", missing)
+	}
+
+	same := runtimeMarker{LauncherVersion: "v1", ProtonPath: marker.ProtonPath, ProtonVersion: marker.ProtonVersion}
+	if reasons := loaded.staleReasons(same); len(reasons) != 0 {
+		t.Fatalf("unexpected stale reasons: %v", reasons)
+	}
+
+	upgraded := same
+	upgraded.ProtonVersion = "1719999999 proton-9.0-3"
+	if reasons := loaded.staleReasons(upgraded); len(reasons) != 1 {
+		t.Fatalf("expected proton upgrade to invalidate marker, got: %v", reasons)
+	}
+}
+
+func TestDetectInstalledVerbs_FromPrefix(t *testing.T) {
+	prefixPath := t.TempDir()
+	systemReg := "WINE REGISTRY Version 2\n\n#arch=win64\n\n" +
+		"[Software\\\\Microsoft\\\\NET Framework Setup\\\\NDP\\\\v4\\\\Full] 1700000000\n" +
+		"\"Release\"=dword:00080ff8\n"
+	if err := os.WriteFile(filepath.Join(prefixPath, "system.reg"), []byte(systemReg), 0o644); err != nil {
+		t.Fatalf("write system.reg: %v", err)
+	}
+	clrDir := filepath.Join(prefixPath, "drive_c", "windows", "Microsoft.NET", "Framework", "v4.0.30319")
+	if err := os.MkdirAll(clrDir, 0o755); err != nil {
+		t.Fatalf("create clr dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clrDir, "clr.dll"), nil, 0o644); err != nil {
+		t.Fatalf("write clr.dll: %v", err)
+	}
+	fontsDir := filepath.Join(prefixPath, "drive_c", "windows", "Fonts")
+	if err := os.MkdirAll(fontsDir, 0o755); err != nil {
+		t.Fatalf("create fonts dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fontsDir, "Arial.TTF"), nil, 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+
+	installed, undetected := detectInstalledVerbs(nil, prefixPath, []string{"dotnet48", "corefonts", "vcrun2019"})
+	if !installed["dotnet48"] {
+		t.Fatal("expected dotnet48 to be detected")
+	}
+	if installed["corefonts"] {
+		t.Fatal("did not expect corefonts with only one font present")
+	}
+	if len(undetected) != 1 || undetected[0] != "vcrun2019" {
+		t.Fatalf("unexpected undetected verbs: %v", undetected)
+	}
+}
+
+func TestParseGameCommandArgs_SteamLinuxRuntimeChain(t *testing.T) {
+	args := []string{
+		"--",
+		"/steam/ubuntu12_32/reaper", "SteamLaunch", "AppId=1245620", "--",
+		"/steam/steamapps/common/SteamLinuxRuntime_sniper/_v2-entry-point", "--verb=waitforexitandrun", "--",
+		"/steam/steamapps/common/Proton 9.0 (Beta)/proton", "waitforexitandrun", "/games/eldenring.exe",
+	}
+	gameCmd, info, err := parseGameCommandArgs(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gameCmd) != len(args)-1 || gameCmd[0] != "/steam/ubuntu12_32/reaper" {
+		t.Fatalf("expected wrappers to be kept, got: %q", gameCmd)
+	}
+	if info != "detected Proton launch command via Steam Linux Runtime" {
+		t.Fatalf("unexpected parse info: %s", info)
+	}
+	if protonPath := protonPathOf(gameCmd); protonPath != "/steam/steamapps/common/Proton 9.0 (Beta)/proton" {
+		t.Fatalf("unexpected proton path: %s", protonPath)
+	}
+}
+
+func TestInspectGameCommand_SniperRunWrapper(t *testing.T) {
+	command := inspectGameCommand([]string{"/lib/SteamLinuxRuntime_sniper/run", "--", "/tools/GE-Proton9-20/proton", "run", "game.exe"})
+	if command.kind != commandSteamRuntime {
+		t.Fatalf("unexpected command kind: %s", command.kind)
+	}
+	if command.protonPath != "/tools/GE-Proton9-20/proton" {
+		t.Fatalf("unexpected proton path: %s", command.protonPath)
+	}
+}
+
+func TestResolveWeModPrefix_Umu(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("WINEPREFIX", "")
+	t.Setenv("GAMEID", "umu-1245620")
+	t.Setenv("PROTONPATH", "/opt/GE-Proton9-20")
+
+	cfg := &config.Config{}
+	cfg.Paths.PrefixDir = "/tmp/own-prefix"
+
+	gameCmd, _, err := parseGameCommandArgs([]string{"umu-run", "/games/eldenring.exe"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prefix, protonMode := resolveWeModPrefix(cfg, gameCmd)
+	if !protonMode {
+		t.Fatal("expected proton mode")
+	}
+	if prefix != filepath.Join(home, "Games", "umu", "umu-1245620") {
+		t.Fatalf("unexpected prefix: %s", prefix)
+	}
+	if protonPath := protonPathOf(gameCmd); protonPath != "/opt/GE-Proton9-20/proton" {
+		t.Fatalf("unexpected proton path: %s", protonPath)
+	}
+}
+
+func TestResolveUmuProtonPath_PicksNewestGE(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PROTONPATH", "GE-Proton")
+	toolsDir := filepath.Join(home, ".local", "share", "Steam", "compatibilitytools.d")
+	for _, name := range []string{"GE-Proton9-9", "GE-Proton9-20", "GE-Proton8-32", "UMU-Proton-9.0-3"} {
+		if err := os.MkdirAll(filepath.Join(toolsDir, name), 0o755); err != nil {
+			t.Fatalf("create tool dir: %v", err)
+		}
+	}
+
+	if got := resolveUmuProtonPath(); got != filepath.Join(toolsDir, "GE-Proton9-20", "proton") {
+		t.Fatalf("unexpected proton path: %s", got)
+	}
+}
+
+func TestSplitLaunchOptions(t *testing.T) {
+	options, rest, err := splitLaunchOptions([]string{"--heroic=Fortnite", "--", "--not-an-option", "game"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options.Heroic != "Fortnite" || len(rest) != 2 || rest[0] != "--not-an-option" {
+		t.Fatalf("unexpected split: %+v %q", options, rest)
+	}
+
+	options, rest, err = splitLaunchOptions([]string{"--lutris", "witcher-3", "/usr/bin/wine", "game.exe"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options.Lutris != "witcher-3" || len(rest) != 2 || rest[0] != "/usr/bin/wine" {
+		t.Fatalf("unexpected split: %+v %q", options, rest)
+	}
+
+	if _, _, err := splitLaunchOptions([]string{"--lutris", "a", "--bottles", "b"}); err == nil {
+		t.Fatal("expected error for conflicting sources")
+	}
+	if _, _, err := splitLaunchOptions([]string{"--bottles"}); err == nil {
+		t.Fatal("expected error for missing value")
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestResolveLaunchTarget_Lutris(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeTestFile(t, filepath.Join(home, ".config", "lutris", "games", "witcher-3-1699999999.yml"), `game:
+  exe: /games/witcher3/bin/x64/witcher3.exe
+  prefix: ~/Games/witcher-3
+system:
+  env:
+    DXVK_HUD: fps
+wine:
+  version: wine-ge-8-26-x86_64
+`, 0o644)
+	wine := filepath.Join(home, ".local", "share", "lutris", "runners", "wine", "wine-ge-8-26-x86_64", "bin", "wine")
+	writeTestFile(t, wine, "#!/bin/sh\n", 0o755)
+
+	target, err := resolveLaunchTarget(&config.Config{}, launchOptions{Lutris: "witcher-3"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Source != sourceLutris || !target.GamePrefix || target.GameID != "witcher-3" {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	if target.Prefix != filepath.Join(home, "Games", "witcher-3") || target.Wine != wine {
+		t.Fatalf("unexpected prefix/wine: %s %s", target.Prefix, target.Wine)
+	}
+}
+
+func TestResolveLaunchTarget_LutrisSlugPrefixOfOtherGame(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	games := filepath.Join(home, ".config", "lutris", "games")
+	writeTestFile(t, filepath.Join(games, "hades-1690000000.yml"), "game:\n  prefix: /games/hades\n", 0o644)
+	writeTestFile(t, filepath.Join(games, "hades-ii-1710000000.yml"), "game:\n  prefix: /games/hades-ii\n", 0o644)
+
+	for slug, want := range map[string]string{"hades": "/games/hades", "hades-ii": "/games/hades-ii"} {
+		target, err := resolveLaunchTarget(&config.Config{}, launchOptions{Lutris: slug}, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", slug, err)
+		}
+		if target.Prefix != want {
+			t.Fatalf("%s: expected prefix %s, got %s", slug, want, target.Prefix)
+		}
+	}
+}
+
+func TestResolveLaunchTarget_HeroicProton(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	protonDir := filepath.Join(home, ".steam", "steam", "compatibilitytools.d", "GE-Proton9-20")
+	writeTestFile(t, filepath.Join(protonDir, "files", "bin", "wine"), "#!/bin/sh\n", 0o755)
+	writeTestFile(t, filepath.Join(home, ".config", "heroic", "GamesConfig", "Fortnite.json"), `{
+  "Fortnite": {
+    "winePrefix": "/games/heroic/Prefixes/Fortnite",
+    "wineVersion": {"bin": "`+filepath.Join(protonDir, "proton")+`", "name": "GE-Proton9-20", "type": "proton"}
+  },
+  "version": "v0"
+}`, 0o644)
+
+	target, err := resolveLaunchTarget(&config.Config{}, launchOptions{Heroic: "Fortnite"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Prefix != "/games/heroic/Prefixes/Fortnite/pfx" {
+		t.Fatalf("unexpected prefix: %s", target.Prefix)
+	}
+	if target.Wine != filepath.Join(protonDir, "files", "bin", "wine") || target.ProtonPath == "" {
+		t.Fatalf("unexpected wine: %+v", target)
+	}
+}
+
+func TestResolveLaunchTarget_BottlesCustomPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dataDir := filepath.Join(home, ".local", "share", "bottles")
+	writeTestFile(t, filepath.Join(dataDir, "bottles", "Gaming", "bottle.yml"), `Arch: win64
+Custom_Path: true
+Environment: Gaming
+Name: Gaming
+Path: /mnt/games/bottles/Gaming
+Runner: soda-9.0-1
+DLLOverrides: {}
+`, 0o644)
+	wine := filepath.Join(dataDir, "runners", "soda-9.0-1", "bin", "wine")
+	writeTestFile(t, wine, "#!/bin/sh\n", 0o755)
+
+	target, err := resolveLaunchTarget(&config.Config{}, launchOptions{Bottles: "Gaming"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Prefix != "/mnt/games/bottles/Gaming" || target.Wine != wine {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+
+	if _, err := resolveLaunchTarget(&config.Config{}, launchOptions{Bottles: "missing"}, nil); err == nil {
+		t.Fatal("expected error for unknown bottle")
+	}
+}
+
+func TestResolveLaunchTarget_CustomPrefixAndWine(t *testing.T) {
+	t.Setenv("WINEPREFIX", "")
+	t.Setenv("SteamAppId", "")
+	t.Setenv("SteamGameId", "")
+	t.Setenv("STEAM_COMPAT_APP_ID", "")
+	t.Setenv("GAMEID", "")
+	t.Setenv("STEAM_COMPAT_DATA_PATH", "")
+	buildDir := filepath.Join(t.TempDir(), "wine-tkg")
+	wine := filepath.Join(buildDir, "bin", "wine")
+	writeTestFile(t, wine, "#!/bin/sh\n", 0o755)
+
+	options, rest, err := splitLaunchOptions([]string{"--prefix", "/games/witcher/pfx", "--wine", buildDir, "--", "/usr/bin/true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	target, err := resolveLaunchTarget(&config.Config{}, options, rest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Source != sourceCustom || !target.GamePrefix || target.Prefix != "/games/witcher/pfx" || target.Wine != wine {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	if env := buildGameEnv(target); env["WINEPREFIX"] != "/games/witcher/pfx" || env["WINE"] != wine {
+		t.Fatalf("unexpected game env: %v", env)
+	}
+
+	// Without --wine the wine build starting the game is used.
+	target, err = resolveLaunchTarget(&config.Config{}, launchOptions{Prefix: "/games/witcher/pfx"}, []string{wine, "witcher3.exe"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Wine != wine {
+		t.Fatalf("expected wine from game command, got %q", target.Wine)
+	}
+
+	if _, err := resolveLaunchTarget(&config.Config{}, launchOptions{Wine: wine}, nil); err == nil {
+		t.Fatal("expected error for --wine without prefix")
+	}
+	if _, _, err := splitLaunchOptions([]string{"--prefix", "/p", "--lutris", "x"}); err == nil {
+		t.Fatal("expected error for --prefix combined with --lutris")
+	}
+}
+
+func TestResolveLaunchTarget_CustomFromGameConfig(t *testing.T) {
+	t.Setenv("WINEPREFIX", "")
+	wine := filepath.Join(t.TempDir(), "wine-ge", "bin", "wine")
+	writeTestFile(t, wine, "#!/bin/sh\n", 0o755)
+
+	cfg := &config.Config{Games: map[string]config.GameConfig{
+		"witcher3": {Prefix: "/games/witcher/pfx", Wine: wine},
+	}}
+	cfg.Paths.PrefixDir = "/tmp/own-prefix"
+
+	target, err := resolveLaunchTarget(cfg, launchOptions{Game: "witcher3"}, []string{"/usr/bin/true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Source != sourceCustom || target.GameID != "witcher3" || target.Prefix != "/games/witcher/pfx" || target.Wine != wine {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+
+	target, err = resolveLaunchTarget(cfg, launchOptions{Game: "other"}, []string{"/usr/bin/true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Source != sourceOwn || target.Prefix != "/tmp/own-prefix" {
+		t.Fatalf("expected own prefix for unconfigured game, got %+v", target)
+	}
+}
+
+func TestWeModProfileChain_RememberedProfileFirst(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Paths.WorkDir = t.TempDir()
+	cfg.WeMod.Fallback = true
+	cfg.WeMod.ProfileChain = []string{"default", "safe", "missing", "custom"}
+	cfg.WeMod.Profiles = map[string]config.WeModProfile{
+		"custom": {Args: []string{"--disable-features=Vulkan"}, Env: map[string]string{"DXVK_HUD": "0"}},
+	}
+
+	names := func(chain []weModProfile) []string {
+		out := make([]string, 0, len(chain))
+		for _, profile := range chain {
+			out = append(out, profile.Name)
+		}
+		return out
+	}
+
+	if got := names(weModProfileChain(cfg, "1245620")); len(got) != 3 || got[0] != "default" || got[2] != "custom" {
+		t.Fatalf("unexpected chain: %v", got)
+	}
+	if err := rememberWeModProfile(cfg.Paths.WorkDir, "1245620", "safe"); err != nil {
+		t.Fatalf("remember profile: %v", err)
+	}
+	chain := weModProfileChain(cfg, "1245620")
+	if got := names(chain); got[0] != "safe" || got[1] != "default" || len(got) != 3 {
+		t.Fatalf("expected remembered profile first: %v", got)
+	}
+	if len(chain[0].Args) == 0 || chain[0].Args[0] != "--disable-gpu" {
+		t.Fatalf("expected builtin safe args, got %q", chain[0].Args)
+	}
+	if got := names(weModProfileChain(cfg, "other")); got[0] != "default" {
+		t.Fatalf("remembered profile must be per game: %v", got)
+	}
+
+	cfg.WeMod.Fallback = false
+	if got := names(weModProfileChain(cfg, "1245620")); len(got) != 1 || got[0] != "safe" {
+		t.Fatalf("expected only the remembered profile without fallback: %v", got)
+	}
+}
+
+func TestWeModProfileChain_AppliesConfiguredArgsAndEnv(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Paths.WorkDir = t.TempDir()
+	cfg.WeMod.ProfileChain = []string{"rescue"}
+	cfg.WeMod.Args = []string{"--enable-logging"}
+	cfg.WeMod.Env = map[string]string{"WINEDEBUG": "-all", "GALLIUM_DRIVER": "zink"}
+	cfg.Games = map[string]config.GameConfig{
+		"1245620": {WeMod: config.WeModProfile{
+			Args: []string{"--lang=de"},
+			Env:  map[string]string{"WINEDEBUG": "+seh", "DXVK_ASYNC": "1"},
+		}},
+	}
+
+	chain := weModProfileChain(cfg, "1245620")
+	if len(chain) != 1 {
+		t.Fatalf("unexpected chain length: %d", len(chain))
+	}
+	profile := chain[0]
+	if last := profile.Args[len(profile.Args)-2:]; last[0] != "--enable-logging" || last[1] != "--lang=de" {
+		t.Fatalf("configured args must follow profile args: %q", profile.Args)
+	}
+	want := map[string]string{"WINEDEBUG": "+seh", "GALLIUM_DRIVER": "zink", "DXVK_ASYNC": "1", "LIBGL_ALWAYS_SOFTWARE": "1"}
+	for key, value := range want {
+		if profile.Env[key] != value {
+			t.Fatalf("env %s = %q, want %q (env=%v)", key, profile.Env[key], value, profile.Env)
+		}
+	}
+
+	if other := weModProfileChain(cfg, "other")[0]; other.Env["WINEDEBUG"] != "-all" || other.Env["DXVK_ASYNC"] != "" {
+		t.Fatalf("per-game settings leaked into other game: %v", other.Env)
+	}
+}
+
+func TestLaunchSession_WaitFollowsRestart(t *testing.T) {
+	startSleep := func(duration string) *wemodRuntime {
+		cmd := exec.Command("sleep", duration)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start sleep: %v", err)
+		}
+		return newWeModRuntime(cmd, "default")
+	}
+	session := newLaunchSession(&config.Config{}, nil, launchTarget{}, nil, compat.Result{}, nil, 0)
+	first := startSleep("0.1")
+	session.update(func() { session.wemod = first; session.restarting = true })
+
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- session.waitWeMod(context.Background()) }()
+
+	<-first.done
+	second := startSleep("0.3")
+	session.update(func() { session.wemod = second; session.restarting = false })
+
+	select {
+	case err := <-waitDone:
+		if !second.exited() {
+			t.Fatalf("waitWeMod returned before the restarted process exited: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitWeMod did not return")
+	}
+}
+
+func TestLaunchSession_StopWeModEndsWaitWithoutError(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start sleep: %v", err)
+	}
+	session := newLaunchSession(&config.Config{}, newTestLogger(t), launchTarget{}, nil, compat.Result{}, nil, 0)
+	session.update(func() { session.wemod = newWeModRuntime(cmd, "default") })
+
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- session.waitWeMod(context.Background()) }()
+	if _, err := session.StopWeMod(); err != nil {
+		t.Fatalf("stop WeMod: %v", err)
+	}
+
+	select {
+	case err := <-waitDone:
+		if err != nil {
+			t.Fatalf("waitWeMod returned %v after stop-wemod", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitWeMod did not return")
+	}
+}
+
+func TestWatchWeMod_RestartsUntilLimit(t *testing.T) {
+	dir := t.TempDir()
+	wine := filepath.Join(dir, "wine")
+	starts := filepath.Join(dir, "starts")
+	if err := os.WriteFile(wine, []byte("#!/bin/sh\necho start >> "+starts+"\nsleep 0.2\n"), 0o755); err != nil {
+		t.Fatalf("write fake wine: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.Paths.WorkDir = dir
+	cfg.Paths.WeModExePath = "WeMod.exe"
+	target := launchTarget{Source: "custom", Prefix: filepath.Join(dir, "pfx")}
+	session := newLaunchSession(cfg, newTestLogger(t), target, map[string]string{"WINE": wine}, compat.Result{}, nil, 0)
+	if _, err := session.startWeMod(context.Background()); err != nil {
+		t.Fatalf("start WeMod: %v", err)
+	}
+
+	watchdog := config.WatchdogConfig{MaxRestarts: 2, BackoffSeconds: 1, MaxBackoffSeconds: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	watched := make(chan struct{})
+	go func() {
+		session.watchWeMod(ctx, watchdog)
+		close(watched)
+	}()
+	select {
+	case <-watched:
+	case <-ctx.Done():
+		t.Fatal("watchdog did not give up")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("watchdog stopped by timeout instead of the restart limit")
+	}
+
+	data, err := os.ReadFile(starts)
+	if err != nil {
+		t.Fatalf("read starts: %v", err)
+	}
+	if got := strings.Count(string(data), "start"); got != 3 {
+		t.Fatalf("expected initial start and 2 restarts, got %d starts", got)
+	}
+	if status := session.Status(); status.WeModRestarts != 2 {
+		t.Fatalf("expected 2 restarts in status, got %d", status.WeModRestarts)
+	}
+}
+
+func TestWatchWeMod_RetriesFailedRestart(t *testing.T) {
+	dir := t.TempDir()
+	wine := filepath.Join(dir, "wine")
+	starts := filepath.Join(dir, "starts")
+	// The first restart exits at once and fails the stability check; the
+	// other starts run long enough to pass it.
+	script := "#!/bin/sh\necho start >> " + starts + "\nif [ \"$(wc -l < " + starts + ")\" -eq 2 ]; then exit 1; fi\nsleep 0.8\n"
+	if err := os.WriteFile(wine, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake wine: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.Paths.WorkDir = dir
+	cfg.Paths.WeModExePath = "WeMod.exe"
+	target := launchTarget{Source: "custom", Prefix: filepath.Join(dir, "pfx")}
+	session := newLaunchSession(cfg, newTestLogger(t), target, map[string]string{"WINE": wine}, compat.Result{}, nil, 300*time.Millisecond)
+	if _, err := session.startWeMod(context.Background()); err != nil {
+		t.Fatalf("start WeMod: %v", err)
+	}
+
+	watchdog := config.WatchdogConfig{MaxRestarts: 2, BackoffSeconds: 1, MaxBackoffSeconds: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	session.watchWeMod(ctx, watchdog)
+	if ctx.Err() != nil {
+		t.Fatal("watchdog stopped retrying after the failed restart")
+	}
+
+	data, err := os.ReadFile(starts)
+	if err != nil {
+		t.Fatalf("read starts: %v", err)
+	}
+	if got := strings.Count(string(data), "start"); got != 3 {
+		t.Fatalf("expected initial start, failed restart and retry, got %d starts", got)
+	}
+	if status := session.Status(); status.WeModRestarts != 1 {
+		t.Fatalf("expected 1 successful restart in status, got %d", status.WeModRestarts)
+	}
+}
+
+func TestWatchdogBackoff(t *testing.T) {
+	watchdog := config.WatchdogConfig{MaxRestarts: 5, BackoffSeconds: 5, MaxBackoffSeconds: 30}
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, expected := range want {
+		if got := watchdog.Backoff(i + 1); got != expected {
+			t.Fatalf("restart %d: expected backoff %s, got %s", i+1, expected, got)
+		}
+	}
+	if got := (config.WatchdogConfig{}).Backoff(3); got != time.Second {
+		t.Fatalf("unset backoff should fall back to 1s, got %s", got)
+	}
+}
+
+func newTestLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "error"
+	logger, err := logging.New(cfg)
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestKillStrayWeMod_SkipsSharedPrefixes(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := t.TempDir()
+	startStray := func() *exec.Cmd {
+		cmd := exec.Command("sh", "-c", "sleep 30; :", "WeMod.exe")
+		cmd.Env = append(os.Environ(), "WINEPREFIX="+prefix)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start stray WeMod: %v", err)
+		}
+		t.Cleanup(func() { _ = cmd.Process.Kill() })
+		go func() { _ = cmd.Wait() }()
+		time.Sleep(100 * time.Millisecond)
+		return cmd
+	}
+	alive := func(cmd *exec.Cmd) bool {
+		return cmd.Process.Signal(syscall.Signal(0)) == nil
+	}
+	logger := newTestLogger(t)
+
+	stray := startStray()
+	newLaunchSession(&config.Config{}, logger, launchTarget{Source: "own", Prefix: prefix}, nil, compat.Result{}, nil, 0).killStrayWeMod()
+	if !alive(stray) {
+		t.Fatal("stray WeMod in the own prefix was killed")
+	}
+
+	game := newLaunchSession(&config.Config{}, logger, launchTarget{Source: "steam", Prefix: prefix, GamePrefix: true}, nil, compat.Result{}, nil, 0)
+	other := registry.Session{PID: os.Getppid(), Source: "steam", Prefix: prefix}
+	if err := registry.Write(other); err != nil {
+		t.Fatalf("write session: %v", err)
+	}
+	game.killStrayWeMod()
+	if !alive(stray) {
+		t.Fatal("stray WeMod in a prefix shared with another launch was killed")
+	}
+
+	if err := registry.Remove(other.PID); err != nil {
+		t.Fatalf("remove session: %v", err)
+	}
+	game.killStrayWeMod()
+	deadline := time.Now().Add(2 * time.Second)
+	for alive(stray) && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if alive(stray) {
+		t.Fatal("stray WeMod in the session's game prefix was not killed")
+	}
+}
+
+func TestLaunchSessionShutdown_ForwardsSignalAndKillsAfterGrace(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Shutdown.GraceSeconds = 1
+	session := newLaunchSession(cfg, newTestLogger(t), launchTarget{}, nil, compat.Result{}, nil, 0)
+
+	for _, tc := range []struct {
+		name     string
+		script   string
+		killed   bool
+		maxDelay time.Duration
+	}{
+		{name: "exits on forwarded signal", script: "exec sleep 30", maxDelay: 900 * time.Millisecond},
+		{name: "killed after grace period", script: `trap "" TERM; exec sleep 30`, killed: true, maxDelay: 5 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			game := exec.Command("sh", "-c", tc.script)
+			if err := game.Start(); err != nil {
+				t.Fatalf("start game: %v", err)
+			}
+			gameExited := make(chan struct{})
+			go func() {
+				_ = game.Wait()
+				close(gameExited)
+			}()
+			time.Sleep(100 * time.Millisecond)
+
+			started := time.Now()
+			session.shutdown(syscall.SIGTERM, game, gameExited)
+			if elapsed := time.Since(started); elapsed > tc.maxDelay {
+				t.Fatalf("shutdown took %s", elapsed)
+			}
+			status := game.ProcessState.Sys().(syscall.WaitStatus)
+			if tc.killed && status.Signal() != syscall.SIGKILL {
+				t.Fatalf("expected game to be killed, got %v", status)
+			}
+			if !tc.killed && status.Signal() != syscall.SIGTERM {
+				t.Fatalf("expected game to exit on SIGTERM, got %v", status)
+			}
+		})
+	}
+}
+
+func TestOpenWeModLog_KeepsNewestLogs(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.General.LogFile = filepath.Join(t.TempDir(), "wemod-launcher.log")
+	cfg.WeMod.Log.Keep = 3
+	dir := cfg.WeModLogDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("create log dir: %v", err)
+	}
+	for _, name := range []string{"wemod-20260101-100000-1a2b3c4d.log", "wemod-20260102-100000-2a2b3c4d.log", "wemod-20260103-100000-3a2b3c4d.log", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	file, err := openWeModLog(cfg, time.Date(2026, 1, 4, 10, 0, 0, 0, time.Local), "0badcafe")
+	if err != nil {
+		t.Fatalf("open WeMod log: %v", err)
+	}
+	defer file.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read log dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{"notes.txt", "wemod-20260102-100000-2a2b3c4d.log", "wemod-20260103-100000-3a2b3c4d.log", "wemod-20260104-100000-0badcafe.log"}
+	if len(names) != len(want) {
+		t.Fatalf("unexpected logs: %v", names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("unexpected logs: %v, want %v", names, want)
+		}
+	}
+}
+
+func TestTruncateWeModLog_KeepsHeadAndContinues(t *testing.T) {
+	file, err := os.OpenFile(filepath.Join(t.TempDir(), "wemod.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	defer file.Close()
+	content := "==== WeMod start ====\n" + strings.Repeat("fixme:heap:noise\n", 20)
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	if truncated, err := truncateWeModLog(file, 1024); err != nil || truncated {
+		t.Fatalf("log below limit truncated: %v, %v", truncated, err)
+	}
+	if truncated, err := truncateWeModLog(file, 100); err != nil || !truncated {
+		t.Fatalf("log above limit not truncated: %v, %v", truncated, err)
+	}
+	if _, err := file.WriteString("err:module:late\n"); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "==== WeMod start ====" || !strings.Contains(lines[2], "dropped") || lines[3] != "err:module:late" {
+		t.Fatalf("unexpected truncated log:\n%s", data)
+	}
+}
+
+func TestStartWeModProcess_WritesOutputToLog(t *testing.T) {
+	t.Setenv("WINEDEBUG", "")
+	dir := t.TempDir()
+	wine := filepath.Join(dir, "wine")
+	if err := os.WriteFile(wine, []byte("#!/bin/sh\necho \"WINEDEBUG=$WINEDEBUG args=$*\"\necho 'err:module:load failed' >&2\n"), 0o755); err != nil {
+		t.Fatalf("write fake wine: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.Paths.WeModExePath = "WeMod.exe"
+	cfg.WeMod.Log.WineDebug = "+seh"
+	output, err := os.Create(filepath.Join(dir, "wemod.log"))
+	if err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	defer output.Close()
+
+	logger := newTestLogger(t)
+	wemodProc, err := startWeModProcess(context.Background(), cfg, logger, map[string]string{"WINE": wine}, weModProfile{Name: "safe", WeModProfile: config.WeModProfile{Args: []string{"--disable-gpu"}}}, output)
+	if err != nil {
+		t.Fatalf("start WeMod: %v", err)
+	}
+	<-wemodProc.done
+
+	data, err := os.ReadFile(output.Name())
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	for _, want := range []string{"WeMod start (session=" + logger.SessionID() + " profile=safe)", "WINEDEBUG=+seh args=WeMod.exe --disable-gpu", "err:module:load failed"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("log misses %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSyncWeModData_PlainWinePrefixUsesPrefixUser(t *testing.T) {
+	t.Setenv("USER", "alice")
+	own := t.TempDir()
+	src := filepath.Join(own, "drive_c", "users", "steamuser", "AppData", "Roaming", "WeMod")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatalf("create own WeMod dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "settings.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	existing := t.TempDir()
+	for _, user := range []string{"Public", "nich"} {
+		if err := os.MkdirAll(filepath.Join(existing, "drive_c", "users", user), 0o755); err != nil {
+			t.Fatalf("create user dir: %v", err)
+		}
+	}
+	fresh := t.TempDir()
+	logger := newTestLogger(t)
+	for prefix, user := range map[string]string{existing: "nich", fresh: "alice"} {
+		if err := syncWeModData(logger, own, launchTarget{Source: "lutris", Prefix: prefix, GamePrefix: true}); err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(prefix, "drive_c", "users", user, "AppData", "Roaming", "WeMod", "settings.json")); err != nil {
+			t.Fatalf("WeMod data not synced for %s: %v", user, err)
+		}
+		if _, err := os.Stat(filepath.Join(prefix, "drive_c", "users", "steamuser")); !os.IsNotExist(err) {
+			t.Fatalf("steamuser created in plain Wine prefix: %v", err)
+		}
+	}
+}
+
+func TestProtonEnv_ExportedToHooksAndWeMod(t *testing.T) {
+	info := &proton.Info{Version: "GE-Proton9-20", Flavor: proton.FlavorGE}
+	target := launchTarget{Source: "steam", Prefix: "/pfx", GamePrefix: true, ProtonPath: "/proton/proton", Proton: info}
+	logger := newTestLogger(t)
+	for name, env := range map[string]map[string]string{"hooks": hookEnv(target), "WeMod": buildWeModEnv(logger, target)} {
+		if env[hooks.EnvProtonVersion] != "GE-Proton9-20" || env[hooks.EnvProtonFlavor] != proton.FlavorGE {
+			t.Fatalf("%s env misses Proton info: %v", name, env)
+		}
+	}
+	if _, ok := hookEnv(launchTarget{Source: "lutris", Prefix: "/pfx"})[hooks.EnvProtonVersion]; ok {
+		t.Fatal("Proton version set for plain Wine target")
+	}
+}
+
+func TestCloneVerbFromPrefix_LeavesRegistryUntouchedOnUserRegError(t *testing.T) {
+	own, game := t.TempDir(), t.TempDir()
+	ownReg := "WINE REGISTRY Version 2\n\n#arch=win64\n\n[Software\\\\Microsoft\\\\NET Framework Setup\\\\NDP\\\\v4\\\\Full] 1700000000\n\"Release\"=dword:00080ff8\n"
+	gameReg := "WINE REGISTRY Version 2\n\n#arch=win64\n\n[Software\\\\Wine] 1700000000\n\"Version\"=\"win10\"\n"
+	files := map[string]string{
+		filepath.Join(own, "system.reg"):                                          ownReg,
+		filepath.Join(game, "system.reg"):                                         gameReg,
+		filepath.Join(own, "drive_c", "windows", "system32", "mscoree.dll"):       "dll",
+		filepath.Join(own, "drive_c", "windows", "Microsoft.NET", "Framework64"):  "dir",
+		filepath.Join(own, "drive_c", "windows", "assembly", "GAC_MSIL", "x.dll"): "dll",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	// The game prefix has no user.reg, so the DLL override cannot be set.
+	if err := cloneVerbFromPrefix(newTestLogger(t), own, game, "dotnet48"); err == nil {
+		t.Fatal("expected error without user.reg")
+	}
+	data, err := os.ReadFile(filepath.Join(game, "system.reg"))
+	if err != nil {
+		t.Fatalf("read system.reg: %v", err)
+	}
+	if string(data) != gameReg {
+		t.Fatalf("system.reg modified although user.reg failed:\n%s", data)
+	}
+}
@@ -0,0 +1,42 @@
+package launch
+
+import (
+	"context"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/prefixlock"
+)
+
+// prefixLocks holds the locks taken for one operation.
+type prefixLocks []*prefixlock.Lock
+
+func (l prefixLocks) release(logger *logging.Logger) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if err := l[i].Release(); err != nil {
+			logger.Warn("%v", err)
+		}
+	}
+}
+
+// lockGamePrefix locks a game prefix exclusively and the own prefix shared,
+// for work that modifies the game prefix and reads from the own prefix
+// (runtime preparation, login sync).
+func lockGamePrefix(ctx context.Context, cfg *config.Config, logger *logging.Logger, gamePrefix, purpose string) (prefixLocks, error) {
+	wait := cfg.LockWait()
+	gameLock, err := prefixlock.Acquire(ctx, gamePrefix, prefixlock.Options{Wait: wait, Purpose: purpose})
+	if err != nil {
+		return nil, err
+	}
+	locks := prefixLocks{gameLock}
+	if prefixlock.Path(gamePrefix) == prefixlock.Path(cfg.Paths.PrefixDir) {
+		return locks, nil
+	}
+	ownLock, err := prefixlock.Own(ctx, cfg, purpose, true)
+	if err != nil {
+		locks.release(logger)
+		return nil, err
+	}
+	logger.Debug("locked prefixes %s (exclusive) and %s (shared) for %s", gamePrefix, cfg.Paths.PrefixDir, purpose)
+	return append(locks, ownLock), nil
+}
@@ -0,0 +1,175 @@
+package launch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+)
+
+const runtimeReadyMarker = ".wemod_launcher_runtime_ready"
+
+// RuntimeMarkerPath returns the path of the runtime-ready marker of a game
+// prefix.
+func RuntimeMarkerPath(prefix string) string {
+	return filepath.Join(prefix, runtimeReadyMarker)
+}
+
+// runtimeMarkerSchemaVersion is bumped whenever the marker layout changes in
+// a way older launchers cannot read.
+const runtimeMarkerSchemaVersion = 1
+
+// runtimeMarker is the JSON content of the runtime-ready marker in a game
+// prefix. It records which verbs were verified and under which launcher and
+// Proton installation, so changes to either trigger a revalidation.
+type runtimeMarker struct {
+	SchemaVersion   int                  `json:"schema_version"`
+	LauncherVersion string               `json:"launcher_version"`
+	ProtonPath      string               `json:"proton_path,omitempty"`
+	ProtonVersion   string               `json:"proton_version,omitempty"`
+	PrefixVersion   string               `json:"prefix_version,omitempty"`
+	Verbs           map[string]time.Time `json:"verbs"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// readRuntimeMarker loads the marker at path. A missing marker returns nil
+// without error. Markers written before the JSON format (plain "ok" or a verb
+// list) are returned with SchemaVersion 0 so they are treated as stale.
+func readRuntimeMarker(path string) (*runtimeMarker, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read runtime marker: %w", err)
+	}
+
+	marker := &runtimeMarker{}
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		marker.Verbs = map[string]time.Time{}
+		return marker, nil
+	}
+	if err := json.Unmarshal(data, marker); err != nil {
+		return nil, fmt.Errorf("decode runtime marker: %w", err)
+	}
+	if marker.Verbs == nil {
+		marker.Verbs = map[string]time.Time{}
+	}
+	return marker, nil
+}
+
+// writeRuntimeMarker stores the marker as indented JSON and stamps UpdatedAt.
+func writeRuntimeMarker(path string, marker *runtimeMarker) error {
+	now := time.Now().UTC()
+	if marker.CreatedAt.IsZero() {
+		marker.CreatedAt = now
+	}
+	marker.UpdatedAt = now
+	marker.SchemaVersion = runtimeMarkerSchemaVersion
+
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode runtime marker: %w", err)
+	}
+	// Write through a temp file so a crash never leaves a truncated marker.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wemod-runtime-*.json")
+	if err != nil {
+		return fmt.Errorf("create runtime marker temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write runtime marker: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close runtime marker: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod runtime marker: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace runtime marker: %w", err)
+	}
+	return nil
+}
+
+// currentRuntimeState describes the environment a marker is validated
+// against. Verbs and timestamps are left empty.
+func currentRuntimeState(target launchTarget) runtimeMarker {
+	state := runtimeMarker{
+		SchemaVersion:   runtimeMarkerSchemaVersion,
+		LauncherVersion: config.AppVersion,
+		PrefixVersion:   readCompatDataVersion(target.Prefix),
+		Verbs:           map[string]time.Time{},
+	}
+	if target.Proton != nil {
+		state.ProtonPath = target.ProtonPath
+		state.ProtonVersion = target.Proton.VersionFile
+	}
+	return state
+}
+
+// staleReasons lists why the marker no longer matches current. Empty fields in
+// current are unknown and not compared.
+func (m *runtimeMarker) staleReasons(current runtimeMarker) []string {
+	var reasons []string
+	if m.SchemaVersion != runtimeMarkerSchemaVersion {
+		reasons = append(reasons, fmt.Sprintf("marker schema %d (want %d)", m.SchemaVersion, runtimeMarkerSchemaVersion))
+	}
+	if current.LauncherVersion != "" && m.LauncherVersion != current.LauncherVersion {
+		reasons = append(reasons, fmt.Sprintf("launcher version %q -> %q", m.LauncherVersion, current.LauncherVersion))
+	}
+	if current.ProtonPath != "" && m.ProtonPath != current.ProtonPath {
+		reasons = append(reasons, fmt.Sprintf("proton path %q -> %q", m.ProtonPath, current.ProtonPath))
+	}
+	if current.ProtonVersion != "" && m.ProtonVersion != current.ProtonVersion {
+		reasons = append(reasons, fmt.Sprintf("proton version %q -> %q", m.ProtonVersion, current.ProtonVersion))
+	}
+	if current.PrefixVersion != "" && m.PrefixVersion != current.PrefixVersion {
+		reasons = append(reasons, fmt.Sprintf("prefix version %q -> %q", m.PrefixVersion, current.PrefixVersion))
+	}
+	return reasons
+}
+
+// missingVerbs returns the required verbs not recorded in the marker,
+// preserving the order of required.
+func (m *runtimeMarker) missingVerbs(required []string) []string {
+	missing := make([]string, 0, len(required))
+	for _, verb := range required {
+		if _, ok := m.Verbs[verb]; !ok {
+			missing = append(missing, verb)
+		}
+	}
+	return missing
+}
+
+// sortedVerbs returns the recorded verbs in alphabetical order.
+func (m *runtimeMarker) sortedVerbs() []string {
+	verbs := make([]string, 0, len(m.Verbs))
+	for verb := range m.Verbs {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	return verbs
+}
+
+// readCompatDataVersion returns the Proton version Steam recorded when it last
+// created or upgraded the compatdata directory containing prefixPath.
+func readCompatDataVersion(prefixPath string) string {
+	if filepath.Base(filepath.Clean(prefixPath)) != "pfx" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(filepath.Clean(prefixPath)), "version"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
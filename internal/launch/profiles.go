@@ -0,0 +1,120 @@
+package launch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+)
+
+// weModProfile is a named set of extra WeMod arguments and env variables.
+type weModProfile struct {
+	Name string
+	config.WeModProfile
+}
+
+// rememberedProfilesFile stores the last working profile per game.
+const rememberedProfilesFile = "wemod-profiles.json"
+
+// profileKey is the remembered-profile key of a game; launches without a game
+// ID share one entry.
+func profileKey(gameID string) string {
+	if gameID == "" {
+		return "default"
+	}
+	return gameID
+}
+
+// weModProfileChain returns the profiles to try for a game. The profile that
+// last worked for the game goes first; without fallback only it is tried.
+// The configured WeMod args/env are applied on top of every profile.
+func weModProfileChain(cfg *config.Config, gameID string) []weModProfile {
+	names := cfg.WeModProfileChain()
+	if remembered := readRememberedProfiles(cfg.Paths.WorkDir)[profileKey(gameID)]; remembered != "" {
+		if _, ok := cfg.WeModProfile(remembered); ok {
+			reordered := []string{remembered}
+			for _, name := range names {
+				if name != remembered {
+					reordered = append(reordered, name)
+				}
+			}
+			names = reordered
+		}
+	}
+	if !cfg.WeMod.Fallback {
+		names = names[:1]
+	}
+
+	settings := cfg.WeModSettings(gameID)
+	chain := make([]weModProfile, 0, len(names))
+	for _, name := range names {
+		profile, _ := cfg.WeModProfile(name)
+		chain = append(chain, weModProfile{Name: name, WeModProfile: applyWeModSettings(profile, settings)})
+	}
+	return chain
+}
+
+// applyWeModSettings layers configured args/env over a profile. Arguments
+// are appended so later flags win; env values replace profile values.
+func applyWeModSettings(profile, settings config.WeModProfile) config.WeModProfile {
+	merged := config.WeModProfile{
+		Args: append(append([]string{}, profile.Args...), settings.Args...),
+		Env:  make(map[string]string, len(profile.Env)+len(settings.Env)),
+	}
+	for key, value := range profile.Env {
+		merged.Env[key] = value
+	}
+	for key, value := range settings.Env {
+		merged.Env[key] = value
+	}
+	return merged
+}
+
+func readRememberedProfiles(workDir string) map[string]string {
+	profiles := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(workDir, rememberedProfilesFile))
+	if err != nil {
+		return profiles
+	}
+	_ = json.Unmarshal(data, &profiles)
+	return profiles
+}
+
+// rememberWeModProfile records the profile that started WeMod for a game.
+func rememberWeModProfile(workDir, gameID, name string) error {
+	profiles := readRememberedProfiles(workDir)
+	key := profileKey(gameID)
+	if profiles[key] == name {
+		return nil
+	}
+	profiles[key] = name
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode remembered profiles: %w", err)
+	}
+	if err := os.MkdirAll(workDir, 0o755); err != nil {
+		return fmt.Errorf("create work dir: %w", err)
+	}
+	path := filepath.Join(workDir, rememberedProfilesFile)
+	tmp, err := os.CreateTemp(workDir, ".wemod-profiles-*.json")
+	if err != nil {
+		return fmt.Errorf("create remembered profiles temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write remembered profiles: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close remembered profiles: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod remembered profiles: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace remembered profiles: %w", err)
+	}
+	return nil
+}
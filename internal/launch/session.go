@@ -0,0 +1,354 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/control"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+// launchSession is the state of a running launch. It starts WeMod and
+// implements the control socket commands.
+type launchSession struct {
+	cfg             *config.Config
+	logger          *logging.Logger
+	target          launchTarget
+	env             map[string]string
+	result          compat.Result
+	gameCmd         []string
+	started         time.Time
+	executable      string
+	stabilityWindow time.Duration
+	// registered is set once the session file was written; changes are
+	// published to it from then on.
+	registered bool
+	publishMu  sync.Mutex
+
+	// starting serializes WeMod starts; restarts are refused while the
+	// initial start is still observed.
+	starting sync.Mutex
+
+	mu         sync.Mutex
+	wemod      *wemodRuntime
+	restarting bool
+	// stopped is set when WeMod was stopped on request; the watchdog does
+	// not restart it then.
+	stopped  bool
+	restarts int
+	gamePID  int
+	// changed is closed and replaced on every update.
+	changed chan struct{}
+
+	// wemodLog receives the output of every WeMod start of the session; it
+	// is opened with the first start. limitWeModLog keeps it below
+	// wemod.log.max_size_mb until logStop is closed.
+	logOnce  sync.Once
+	wemodLog *os.File
+	logStop  chan struct{}
+	logDone  chan struct{}
+}
+
+func newLaunchSession(cfg *config.Config, logger *logging.Logger, target launchTarget, env map[string]string, result compat.Result, gameCmd []string, stabilityWindow time.Duration) *launchSession {
+	executable, _ := os.Executable()
+	return &launchSession{
+		cfg:             cfg,
+		logger:          logger,
+		target:          target,
+		env:             env,
+		result:          result,
+		gameCmd:         gameCmd,
+		started:         time.Now(),
+		executable:      executable,
+		stabilityWindow: stabilityWindow,
+		changed:         make(chan struct{}),
+	}
+}
+
+// update changes the session state under the lock, wakes up waitWeMod and
+// publishes the new state to the session registry.
+func (s *launchSession) update(change func()) {
+	s.mu.Lock()
+	change()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+	s.publish()
+}
+
+// register writes the session file; the returned function removes it.
+func (s *launchSession) register() func() {
+	s.mu.Lock()
+	s.registered = true
+	s.mu.Unlock()
+	s.publish()
+	return func() {
+		s.mu.Lock()
+		s.registered = false
+		s.mu.Unlock()
+		if err := registry.Remove(os.Getpid()); err != nil {
+			s.logger.Warn("failed removing session file: %v", err)
+		}
+	}
+}
+
+// publish writes the current state to the session file. publishMu keeps
+// concurrent callers from replacing a newer state with an older one.
+func (s *launchSession) publish() {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	s.mu.Lock()
+	registered := s.registered
+	s.mu.Unlock()
+	if !registered {
+		return
+	}
+	if err := registry.Write(s.Status()); err != nil {
+		s.logger.Warn("failed writing session file: %v", err)
+	}
+}
+
+func (s *launchSession) currentWeMod() *wemodRuntime {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wemod
+}
+
+func (s *launchSession) setGamePID(pid int) {
+	s.update(func() { s.gamePID = pid })
+}
+
+// startWeMod starts WeMod with the profile fallback chain.
+func (s *launchSession) startWeMod(ctx context.Context) (*wemodRuntime, error) {
+	s.starting.Lock()
+	defer s.starting.Unlock()
+	wemodProc, err := startWeModWithFallback(ctx, s.cfg, s.logger, s.target, s.env, s.result, s.stabilityWindow, s.weModOutput())
+	if err != nil {
+		return nil, err
+	}
+	s.update(func() { s.wemod = wemodProc })
+	return wemodProc, nil
+}
+
+// waitWeMod blocks until WeMod exits, following restarts requested over the
+// control socket. It returns the exit error of the last WeMod process, or nil
+// when WeMod was stopped on request.
+func (s *launchSession) waitWeMod(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		wemodProc, restarting, changed := s.wemod, s.restarting, s.changed
+		s.mu.Unlock()
+		if wemodProc == nil && !restarting {
+			return nil
+		}
+
+		var done <-chan struct{}
+		if wemodProc != nil && !restarting {
+			done = wemodProc.done
+		}
+		select {
+		case <-done:
+			s.mu.Lock()
+			final := s.wemod == wemodProc && !s.restarting
+			stopped := s.stopped
+			s.mu.Unlock()
+			if final && stopped {
+				return nil
+			}
+			if final {
+				return wemodProc.err
+			}
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stopCurrentWeMod stops the WeMod process group and waits for it to exit.
+func (s *launchSession) stopCurrentWeMod() (*wemodRuntime, error) {
+	wemodProc := s.currentWeMod()
+	if wemodProc == nil || wemodProc.exited() {
+		return nil, nil
+	}
+	if err := stopWeModProcessGroup(wemodProc.pid()); err != nil {
+		return wemodProc, err
+	}
+	select {
+	case <-wemodProc.done:
+	case <-time.After(5 * time.Second):
+		s.logger.Warn("timeout waiting for WeMod process %d to exit", wemodProc.pid())
+	}
+	return wemodProc, nil
+}
+
+// weModOutput returns the WeMod log of the session, or nil when WeMod output
+// is discarded.
+func (s *launchSession) weModOutput() *os.File {
+	s.logOnce.Do(func() {
+		if !s.cfg.WeMod.Log.Enabled {
+			return
+		}
+		file, err := openWeModLog(s.cfg, s.started, s.logger.SessionID())
+		if err != nil {
+			s.logger.Warn("WeMod output will be discarded: %v", err)
+			return
+		}
+		s.logger.Info("WeMod output log: %s", file.Name())
+		s.mu.Lock()
+		s.wemodLog = file
+		s.mu.Unlock()
+		s.logStop = make(chan struct{})
+		s.logDone = make(chan struct{})
+		go s.limitWeModLog(int64(s.cfg.WeMod.Log.MaxSizeMB) << 20)
+	})
+	return s.wemodLog
+}
+
+// limitWeModLog truncates the WeMod log whenever it grows beyond maxSize.
+func (s *launchSession) limitWeModLog(maxSize int64) {
+	defer close(s.logDone)
+	if maxSize <= 0 {
+		return
+	}
+	ticker := time.NewTicker(weModLogCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.logStop:
+			return
+		case <-ticker.C:
+		}
+		truncated, err := truncateWeModLog(s.wemodLog, maxSize)
+		if err != nil {
+			s.logger.Warn("failed limiting WeMod log: %v", err)
+		} else if truncated {
+			s.logger.Info("WeMod log reached %d MB; truncated %s", maxSize>>20, s.wemodLog.Name())
+		}
+	}
+}
+
+// closeWeModLog closes the launcher's handle of the WeMod log. WeMod keeps
+// writing to it while it runs, without the size limit.
+func (s *launchSession) closeWeModLog() {
+	s.logOnce.Do(func() {})
+	if s.wemodLog != nil {
+		close(s.logStop)
+		<-s.logDone
+		_ = s.wemodLog.Close()
+	}
+}
+
+// Status implements control.Handler.
+func (s *launchSession) Status() registry.Session {
+	status := registry.Session{
+		PID:         os.Getpid(),
+		SessionID:   s.logger.SessionID(),
+		Executable:  s.executable,
+		StartedAt:   s.started,
+		GameID:      s.target.GameID,
+		Source:      s.target.Source,
+		Prefix:      s.target.Prefix,
+		Proton:      s.target.ProtonPath,
+		ProtonPath:  s.target.ProtonPath,
+		Wine:        s.target.Wine,
+		GameCommand: s.gameCmd,
+	}
+	if s.target.Proton != nil {
+		status.Proton = s.target.Proton.String()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status.GamePID = s.gamePID
+	status.WeModRestarts = s.restarts
+	if s.wemod != nil && !s.wemod.exited() {
+		status.WeModPID = s.wemod.pid()
+		status.WeModProfile = s.wemod.profile
+	}
+	if s.wemodLog != nil {
+		status.WeModLog = s.wemodLog.Name()
+	}
+	return status
+}
+
+// RestartWeMod implements control.Handler.
+func (s *launchSession) RestartWeMod(ctx context.Context) (string, error) {
+	if !s.starting.TryLock() {
+		return "", errors.New("WeMod is still starting; try again later")
+	}
+	defer s.starting.Unlock()
+
+	s.update(func() { s.restarting = true; s.stopped = false })
+	defer s.update(func() { s.restarting = false })
+
+	if old, err := s.stopCurrentWeMod(); err != nil {
+		return "", fmt.Errorf("stop WeMod: %w", err)
+	} else if old != nil {
+		s.logger.Info("stopped WeMod (pid=%d) for restart", old.pid())
+	}
+	userNotice("Restarting WeMod ...")
+	wemodProc, err := startWeModWithFallback(ctx, s.cfg, s.logger, s.target, s.env, s.result, s.stabilityWindow, s.weModOutput())
+	if err != nil {
+		s.update(func() { s.wemod = nil })
+		return "", fmt.Errorf("start wemod: %w", err)
+	}
+	s.update(func() { s.wemod = wemodProc })
+	return fmt.Sprintf("WeMod restarted (pid=%d profile=%s)", wemodProc.pid(), wemodProc.profile), nil
+}
+
+// StopWeMod implements control.Handler.
+func (s *launchSession) StopWeMod() (string, error) {
+	s.update(func() { s.stopped = true })
+	wemodProc, err := s.stopCurrentWeMod()
+	if err != nil {
+		return "", fmt.Errorf("stop WeMod: %w", err)
+	}
+	if wemodProc == nil {
+		return "", errors.New("WeMod is not running")
+	}
+	s.publish()
+	return fmt.Sprintf("WeMod stopped (pid=%d)", wemodProc.pid()), nil
+}
+
+// SyncNow implements control.Handler.
+func (s *launchSession) SyncNow() (string, error) {
+	if !s.target.GamePrefix {
+		return "", errors.New("WeMod runs in its own prefix; nothing to sync")
+	}
+	locks, err := lockGamePrefix(context.Background(), s.cfg, s.logger, s.target.Prefix, "sync-now")
+	if err != nil {
+		return "", err
+	}
+	defer locks.release(s.logger)
+	if err := syncWeModData(s.logger, s.cfg.Paths.PrefixDir, s.target); err != nil {
+		return "", err
+	}
+	return "WeMod data synced into " + s.target.Prefix, nil
+}
+
+// serveControl exposes the session on the control socket. Commands run with
+// ctx; the returned function closes the socket and may be called repeatedly.
+func (s *launchSession) serveControl(ctx context.Context) func() {
+	server, err := control.Listen()
+	if err != nil {
+		s.logger.Warn("control socket unavailable: %v", err)
+		return func() {}
+	}
+	s.logger.Info("control socket: %s", server.Path())
+	go server.Serve(ctx, s.logger, s)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if err := server.Close(); err != nil {
+				s.logger.Warn("failed closing control socket: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+	process "github.com/NichSchlagen/wemod-proton-launcher-go/internal/runtime"
+)
+
+// shutdownSignals end a launch; they also cancel the launcher context.
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// receivedSignal returns the pending shutdown signal, or SIGTERM when the
+// launch was cancelled otherwise.
+func receivedSignal(signals <-chan os.Signal) os.Signal {
+	select {
+	case sig := <-signals:
+		return sig
+	default:
+		return syscall.SIGTERM
+	}
+}
+
+// wineserverKillTimeout bounds "wineserver -k".
+const wineserverKillTimeout = 10 * time.Second
+
+// shutdown stops a session after a termination signal: the signal is
+// forwarded to the game, WeMod is stopped and the game gets the configured
+// grace period before it is killed. With shutdown.kill_wineserver the
+// remaining Wine processes of the prefix are stopped as well. gameProc is nil
+// in standalone mode.
+func (s *launchSession) shutdown(sig os.Signal, gameProc *exec.Cmd, gameExited <-chan struct{}) {
+	logger := s.logger.WithComponent("launch.shutdown")
+	logger.Info("received %v, shutting down", sig)
+
+	if gameProc != nil {
+		if err := gameProc.Process.Signal(sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			logger.Warn("failed forwarding %v to game (pid=%d): %v", sig, gameProc.Process.Pid, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	if wemodProc, err := s.stopCurrentWeMod(); err != nil {
+		logger.Warn("failed to stop WeMod process group: %v", err)
+	} else if wemodProc != nil {
+		logger.Info("WeMod stopped (pid=%d)", wemodProc.pid())
+	}
+	s.killStrayWeMod()
+
+	if gameProc != nil {
+		grace := s.cfg.Shutdown.Grace()
+		select {
+		case <-gameExited:
+			logger.Info("game exited after %v", sig)
+		case <-time.After(grace):
+			logger.Warn("game did not exit within %s; killing it (pid=%d)", grace, gameProc.Process.Pid)
+			_ = gameProc.Process.Kill()
+			<-gameExited
+		}
+	}
+
+	if s.cfg.Shutdown.KillWineserver {
+		s.killWineserver()
+	}
+}
+
+// killStrayWeMod kills WeMod processes of the session prefix that left the
+// WeMod process group, e.g. helpers re-parented to the wineserver. Prefixes
+// shared with other launches, like the own prefix, are left alone: their
+// WeMod processes cannot be told apart.
+func (s *launchSession) killStrayWeMod() {
+	if !s.target.GamePrefix {
+		return
+	}
+	sessions, err := registry.List()
+	if err != nil {
+		s.logger.Warn("not killing stray WeMod processes: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if session.PID != os.Getpid() && session.Prefix == s.target.Prefix {
+			s.logger.Debug("prefix %s is shared with launcher pid %d; not killing stray WeMod processes", s.target.Prefix, session.PID)
+			return
+		}
+	}
+	for _, proc := range process.ScanPrefix(s.target.Prefix).WeMod {
+		s.logger.Debug("killing stray WeMod process %s (pid=%d)", proc.Name(), proc.PID)
+		if err := syscall.Kill(proc.PID, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			s.logger.Warn("failed to kill WeMod process %d: %v", proc.PID, err)
+		}
+	}
+}
+
+// killWineserver runs "wineserver -k" for the session prefix with the
+// wineserver of the session's wine build.
+func (s *launchSession) killWineserver() {
+	wineserver := s.env["WINESERVER"]
+	if wineserver == "" {
+		wineserver = "wineserver"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wineserverKillTimeout)
+	defer cancel()
+	s.logger.Info("stopping wineserver of %s (%s -k)", s.target.Prefix, wineserver)
+	env := map[string]string{"WINEPREFIX": s.target.Prefix}
+	if err := process.Run(ctx, s.logger, wineserver, []string{"-k"}, env); err != nil {
+		s.logger.Warn("wineserver -k failed: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package launch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolveLutrisTarget reads the Lutris game config for slug. Lutris stores
+// one YAML file per game (<slug>-<id>.yml) with the prefix under game.prefix
+// and the wine runner version under wine.version.
+func resolveLutrisTarget(slug string) (launchTarget, error) {
+	dataDirs := lutrisDataDirs()
+	configPath := ""
+	for _, dir := range dataDirs {
+		var matches []string
+		candidates, _ := filepath.Glob(filepath.Join(dir.config, "games", slug+"-*.yml"))
+		for _, candidate := range candidates {
+			// hades-ii-<id>.yml must not match --lutris hades.
+			if isDigits(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(candidate), slug+"-"), ".yml")) {
+				matches = append(matches, candidate)
+			}
+		}
+		exact := filepath.Join(dir.config, "games", slug+".yml")
+		if _, err := os.Stat(exact); err == nil {
+			matches = append(matches, exact)
+		}
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			configPath = matches[len(matches)-1]
+			break
+		}
+	}
+	if configPath == "" {
+		return launchTarget{}, fmt.Errorf("no Lutris game config found for %q", slug)
+	}
+
+	values, err := readSimpleYAML(configPath)
+	if err != nil {
+		return launchTarget{}, fmt.Errorf("read Lutris config: %w", err)
+	}
+	prefix := expandHome(values["game.prefix"])
+	if prefix == "" {
+		return launchTarget{}, fmt.Errorf("Lutris config %s has no game.prefix", configPath)
+	}
+
+	version := values["wine.version"]
+	if version == "" {
+		for _, dir := range dataDirs {
+			runnerValues, err := readSimpleYAML(filepath.Join(dir.config, "runners", "wine.yml"))
+			if err == nil && runnerValues["wine.version"] != "" {
+				version = runnerValues["wine.version"]
+				break
+			}
+		}
+	}
+
+	target := launchTarget{Source: sourceLutris, GameID: slug, Prefix: prefix, GamePrefix: true}
+	if version != "" && !strings.EqualFold(version, "system") {
+		for _, dir := range dataDirs {
+			candidate := filepath.Join(dir.data, "runners", "wine", version, "bin", "wine")
+			if isExecutable(candidate) {
+				target.Wine = candidate
+				break
+			}
+		}
+		if target.Wine == "" {
+			return launchTarget{}, fmt.Errorf("Lutris wine runner %q not found", version)
+		}
+	}
+	return target, nil
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type lutrisDirs struct {
+	config string
+	data   string
+}
+
+func lutrisDataDirs() []lutrisDirs {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []lutrisDirs{
+		{config: filepath.Join(home, ".config", "lutris"), data: filepath.Join(home, ".local", "share", "lutris")},
+		// Older Lutris releases kept game configs in the data dir.
+		{config: filepath.Join(home, ".local", "share", "lutris"), data: filepath.Join(home, ".local", "share", "lutris")},
+		{config: filepath.Join(home, ".var", "app", "net.lutris.Lutris", "config", "lutris"), data: filepath.Join(home, ".var", "app", "net.lutris.Lutris", "data", "lutris")},
+	}
+}
+
+// heroicGameConfig is the part of GamesConfig/<appName>.json the launcher uses.
+type heroicGameConfig struct {
+	WinePrefix  string `json:"winePrefix"`
+	WineVersion struct {
+		Bin  string `json:"bin"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"wineVersion"`
+}
+
+// resolveHeroicTarget reads Heroic's per-game config. For Proton runners the
+// configured prefix is a compatdata dir and the wine prefix is its pfx subdir.
+func resolveHeroicTarget(appName string) (launchTarget, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return launchTarget{}, fmt.Errorf("resolve home dir: %w", err)
+	}
+	configDirs := []string{
+		filepath.Join(home, ".config", "heroic", "GamesConfig"),
+		filepath.Join(home, ".var", "app", "com.heroicgameslauncher.hgl", "config", "heroic", "GamesConfig"),
+	}
+
+	var data []byte
+	configPath := ""
+	for _, dir := range configDirs {
+		candidate := filepath.Join(dir, appName+".json")
+		if data, err = os.ReadFile(candidate); err == nil {
+			configPath = candidate
+			break
+		}
+	}
+	if configPath == "" {
+		return launchTarget{}, fmt.Errorf("no Heroic game config found for %q", appName)
+	}
+
+	var configs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return launchTarget{}, fmt.Errorf("decode Heroic config %s: %w", configPath, err)
+	}
+	raw, ok := configs[appName]
+	if !ok {
+		return launchTarget{}, fmt.Errorf("Heroic config %s has no entry for %q", configPath, appName)
+	}
+	var game heroicGameConfig
+	if err := json.Unmarshal(raw, &game); err != nil {
+		return launchTarget{}, fmt.Errorf("decode Heroic config %s: %w", configPath, err)
+	}
+	if strings.TrimSpace(game.WinePrefix) == "" {
+		return launchTarget{}, fmt.Errorf("Heroic config %s has no winePrefix", configPath)
+	}
+
+	target := launchTarget{Source: sourceHeroic, GameID: appName, Prefix: expandHome(game.WinePrefix), GamePrefix: true}
+	switch strings.ToLower(game.WineVersion.Type) {
+	case "proton":
+		target.Prefix = filepath.Join(target.Prefix, "pfx")
+		target.setProton(game.WineVersion.Bin)
+		if target.Wine == "" {
+			return launchTarget{}, fmt.Errorf("could not resolve wine binary of Heroic Proton runner %s", game.WineVersion.Bin)
+		}
+	default:
+		if bin := strings.TrimSpace(game.WineVersion.Bin); bin != "" {
+			target.Wine = bin
+		}
+	}
+	return target, nil
+}
+
+// resolveBottlesTarget reads bottle.yml of the named bottle. The bottle
+// directory is the prefix; the runner lives in the Bottles runners dir.
+func resolveBottlesTarget(name string) (launchTarget, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return launchTarget{}, fmt.Errorf("resolve home dir: %w", err)
+	}
+	dataDirs := []string{
+		filepath.Join(home, ".local", "share", "bottles"),
+		filepath.Join(home, ".var", "app", "com.usebottles.bottles", "data", "bottles"),
+	}
+
+	for _, dataDir := range dataDirs {
+		bottleDir := filepath.Join(dataDir, "bottles", name)
+		values, err := readSimpleYAML(filepath.Join(bottleDir, "bottle.yml"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return launchTarget{}, fmt.Errorf("read bottle config: %w", err)
+		}
+
+		prefix := bottleDir
+		if strings.EqualFold(values["Custom_Path"], "true") && values["Path"] != "" {
+			prefix = expandHome(values["Path"])
+		}
+		target := launchTarget{Source: sourceBottles, GameID: name, Prefix: prefix, GamePrefix: true}
+
+		runner := values["Runner"]
+		if runner != "" && !strings.HasPrefix(runner, "sys-") {
+			candidate := filepath.Join(dataDir, "runners", runner, "bin", "wine")
+			if !isExecutable(candidate) {
+				return launchTarget{}, fmt.Errorf("Bottles runner %q not found", runner)
+			}
+			target.Wine = candidate
+		}
+		return target, nil
+	}
+	return launchTarget{}, fmt.Errorf("no bottle named %q found", name)
+}
+
+// readSimpleYAML reads the block-mapping subset of YAML used by Lutris and
+// Bottles configs. Nested keys are joined with dots ("game.prefix"); lists
+// and flow collections are skipped.
+func readSimpleYAML(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	type level struct {
+		indent int
+		key    string
+	}
+	var stack []level
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		key = unquoteYAML(strings.TrimSpace(key))
+		fullKey := key
+		if len(stack) > 0 {
+			fullKey = stack[len(stack)-1].key + "." + key
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			stack = append(stack, level{indent: indent, key: fullKey})
+			continue
+		}
+		if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+			continue
+		}
+		values[fullKey] = unquoteYAML(value)
+	}
+	return values, nil
+}
+
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+func expandHome(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+func isExecutable(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir() && st.Mode()&0o111 != 0
+}
@@ -0,0 +1,251 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	process "github.com/NichSchlagen/wemod-proton-launcher-go/internal/runtime"
+)
+
+// Startup observation timings, matching scripts/wand_probe.py.
+const (
+	startupObserveTimeout = 30 * time.Second
+	rendererStableWindow  = 2 * time.Second
+	blackPatternWindow    = 5 * time.Second
+	startupPollInterval   = 250 * time.Millisecond
+)
+
+// weModProcessState counts the Electron processes of a WeMod instance.
+type weModProcessState struct {
+	Main     int
+	Renderer int
+	GPU      int
+	Utility  int
+}
+
+func (s weModProcessState) String() string {
+	return fmt.Sprintf("main=%d renderer=%d gpu=%d utility=%d", s.Main, s.Renderer, s.GPU, s.Utility)
+}
+
+// inspectWeModProcesses classifies the WeMod/Wand processes running in the
+// given prefix by their Electron --type argument.
+func inspectWeModProcesses(prefix string) weModProcessState {
+	var state weModProcessState
+	for _, proc := range process.ScanPrefix(prefix).WeMod {
+		switch args := strings.ToLower(strings.Join(proc.Args, " ")); {
+		case strings.Contains(args, "--type=renderer"):
+			state.Renderer++
+		case strings.Contains(args, "--type=gpu-process"):
+			state.GPU++
+		case strings.Contains(args, "--type=utility"):
+			state.Utility++
+		default:
+			state.Main++
+		}
+	}
+	return state
+}
+
+// startupObservation is the classified outcome of a WeMod start.
+type startupObservation struct {
+	Outcome string
+	Detail  string
+	// Seen is true when any WeMod process was found in the prefix. Without
+	// it the outcome says nothing about the renderer.
+	Seen bool
+}
+
+// observeWeModStartup watches the WeMod processes in prefix until the
+// renderer is stable, the black-window pattern persists, WeMod exits or the
+// timeout passes. A cancelled ctx returns an empty outcome.
+func observeWeModStartup(ctx context.Context, pid int, prefix string, timeout time.Duration) startupObservation {
+	deadline := time.Now().Add(timeout)
+	var rendererSince, blackSince time.Time
+	var state weModProcessState
+	seen := false
+	for time.Now().Before(deadline) {
+		state = inspectWeModProcesses(prefix)
+		now := time.Now()
+		seen = seen || state != (weModProcessState{})
+
+		if state.Renderer > 0 {
+			if rendererSince.IsZero() {
+				rendererSince = now
+			}
+		} else {
+			rendererSince = time.Time{}
+		}
+		if state.Main > 0 && state.GPU > 0 && state.Utility > 0 && state.Renderer == 0 {
+			if blackSince.IsZero() {
+				blackSince = now
+			}
+		} else {
+			blackSince = time.Time{}
+		}
+
+		switch {
+		case !rendererSince.IsZero() && now.Sub(rendererSince) >= rendererStableWindow:
+			return startupObservation{compat.OutcomeStarted, "renderer stable (" + state.String() + ")", true}
+		case !blackSince.IsZero() && now.Sub(blackSince) >= blackPatternWindow:
+			return startupObservation{compat.OutcomeBlackPattern, "main+gpu+utility without renderer (" + state.String() + ")", true}
+		case !processAlive(pid) && state == (weModProcessState{}):
+			return startupObservation{compat.OutcomeExited, "WeMod exited during startup", true}
+		}
+
+		select {
+		case <-ctx.Done():
+			return startupObservation{}
+		case <-time.After(startupPollInterval):
+		}
+	}
+	return startupObservation{compat.OutcomeTimeout, fmt.Sprintf("no stable renderer within %s (%s)", timeout, state), seen}
+}
+
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// newCompatResult describes the WeMod/Proton combination of a launch.
+func newCompatResult(cfg *config.Config, target launchTarget) compat.Result {
+	product, version := compat.DetectWeModVersion(cfg.Paths.WeModExePath)
+	result := compat.Result{
+		Product:       product,
+		WeModVersion:  version,
+		ProtonVersion: runtimeLabel(target),
+		GameID:        target.GameID,
+	}
+	if target.Proton != nil {
+		result.ProtonFlavor = target.Proton.Flavor
+	}
+	return result
+}
+
+// runtimeLabel names the Wine runtime of a target for the compat database:
+// the Proton release, the wine build directory or "system-wine".
+func runtimeLabel(target launchTarget) string {
+	if target.Proton != nil {
+		if target.Proton.Version != "" {
+			return target.Proton.Version
+		}
+		return target.Proton.Name
+	}
+	if target.Wine != "" {
+		return filepath.Base(filepath.Dir(filepath.Dir(target.Wine)))
+	}
+	return "system-wine"
+}
+
+// checkCompatibility applies compat.mode to a known-bad combination. It
+// returns true when WeMod should not be started. Without a game command,
+// refusing is an error.
+func checkCompatibility(cfg *config.Config, logger *logging.Logger, result compat.Result, standalone bool) (bool, error) {
+	logger = logger.WithComponent("launch.compat")
+	mode := cfg.CompatMode()
+	if mode == compat.ModeOff {
+		return false, nil
+	}
+	if result.WeModVersion == "" {
+		logger.Debug("WeMod version unknown; skipping compatibility check")
+		return false, nil
+	}
+	logger.Info("compatibility: %s %s with %s", result.Product, result.WeModVersion, result.ProtonVersion)
+
+	db, err := compat.Load(compat.DefaultPath(cfg.Paths.WorkDir))
+	if err != nil {
+		logger.Warn("failed loading compat results: %v", err)
+	}
+	verdict := db.Check(result.Product, result.WeModVersion, result.ProtonVersion)
+	if !verdict.Bad {
+		return false, nil
+	}
+
+	if mode != compat.ModeRefuse {
+		logger.Warn("known-bad combination (%s): %s", verdict.Source, verdict.Reason)
+		userNotice("Warning: %s %s is known not to work with %s: %s", result.Product, result.WeModVersion, result.ProtonVersion, verdict.Reason)
+		return false, nil
+	}
+	logger.Error("refusing known-bad combination (%s): %s", verdict.Source, verdict.Reason)
+	if standalone {
+		return true, fmt.Errorf("refusing to start %s %s with %s: %s (set compat.mode = \"warn\" to override)", result.Product, result.WeModVersion, result.ProtonVersion, verdict.Reason)
+	}
+	userNotice("Not starting %s %s with %s: %s. Starting the game without it.", result.Product, result.WeModVersion, result.ProtonVersion, verdict.Reason)
+	return true, nil
+}
+
+// startWeModWithFallback starts WeMod with the profiles of the game's chain
+// until its renderer comes up. Each attempt is recorded in the compat
+// database and the working profile is remembered for the game. When every
+// profile fails, the last WeMod process is kept running.
+func startWeModWithFallback(ctx context.Context, cfg *config.Config, logger *logging.Logger, target launchTarget, env map[string]string, result compat.Result, stabilityWindow time.Duration, output *os.File) (*wemodRuntime, error) {
+	chain := weModProfileChain(cfg, target.GameID)
+	var lastErr error
+	for i, profile := range chain {
+		last := i == len(chain)-1
+		wemodProc, err := startWeModProcessWithRecovery(ctx, cfg, logger, env, profile, output)
+		if err != nil {
+			return nil, err
+		}
+		pid := wemodProc.pid()
+		result.Profile = profile.Name
+
+		if err := ensureProcessRunning(pid, stabilityWindow); err != nil {
+			saveCompatResult(cfg, logger, result, compat.OutcomeExited, err.Error())
+			lastErr = err
+			if last {
+				break
+			}
+			logger.Warn("WeMod exited with profile %s; retrying with profile %s", profile.Name, chain[i+1].Name)
+			continue
+		}
+		logger.Info("WeMod started (pid=%d profile=%s)", pid, profile.Name)
+
+		observation := observeWeModStartup(ctx, pid, target.Prefix, startupObserveTimeout)
+		if observation.Outcome == "" {
+			return wemodProc, nil
+		}
+		logger.Info("WeMod startup outcome: %s (%s)", observation.Outcome, observation.Detail)
+		saveCompatResult(cfg, logger, result, observation.Outcome, observation.Detail)
+		if observation.Outcome == compat.OutcomeStarted {
+			if err := rememberWeModProfile(cfg.Paths.WorkDir, target.GameID, profile.Name); err != nil {
+				logger.Warn("failed remembering WeMod profile: %v", err)
+			}
+			return wemodProc, nil
+		}
+		if last || !observation.Seen {
+			return wemodProc, nil
+		}
+
+		next := chain[i+1].Name
+		logger.Warn("WeMod renderer did not start with profile %s; restarting with profile %s", profile.Name, next)
+		userNotice("WeMod window did not appear, restarting with %s profile ...", next)
+		if err := stopWeModProcessGroup(pid); err != nil {
+			logger.Warn("failed to stop WeMod process group: %v", err)
+		}
+		<-wemodProc.done
+	}
+	return nil, lastErr
+}
+
+func saveCompatResult(cfg *config.Config, logger *logging.Logger, result compat.Result, outcome, detail string) {
+	if result.WeModVersion == "" {
+		return
+	}
+	result.Outcome = outcome
+	result.Detail = detail
+	result.Time = time.Now()
+	if err := compat.Record(compat.DefaultPath(cfg.Paths.WorkDir), result); err != nil {
+		logger.Warn("failed recording compat result: %v", err)
+	}
+}
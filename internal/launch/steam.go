@@ -0,0 +1,69 @@
+package launch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var libraryPathPattern = regexp.MustCompile(`"path"\s+"([^"]+)"`)
+
+// steamRoots returns candidate Steam installation directories, starting with
+// the one Steam passes to compatibility tools.
+func steamRoots() []string {
+	roots := make([]string, 0, 4)
+	if client := strings.TrimSpace(os.Getenv("STEAM_COMPAT_CLIENT_INSTALL_PATH")); client != "" {
+		roots = append(roots, client)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots,
+			filepath.Join(home, ".steam", "steam"),
+			filepath.Join(home, ".local", "share", "Steam"),
+			filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		)
+	}
+	return roots
+}
+
+// steamLibraryDirs returns all Steam library folders, including the ones
+// listed in each root's libraryfolders.vdf. Duplicates are removed.
+func steamLibraryDirs() []string {
+	seen := map[string]bool{}
+	libraries := make([]string, 0)
+	add := func(dir string) {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return
+		}
+		if seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		libraries = append(libraries, resolved)
+	}
+
+	for _, root := range steamRoots() {
+		add(root)
+		data, err := os.ReadFile(filepath.Join(root, "steamapps", "libraryfolders.vdf"))
+		if err != nil {
+			continue
+		}
+		for _, match := range libraryPathPattern.FindAllStringSubmatch(string(data), -1) {
+			add(strings.ReplaceAll(match[1], `\\`, `\`))
+		}
+	}
+	return libraries
+}
+
+// findCompatDataPrefix locates steamapps/compatdata/<appid>/pfx in any Steam library.
+func findCompatDataPrefix(appID string) (string, error) {
+	for _, library := range steamLibraryDirs() {
+		candidate := filepath.Join(library, "steamapps", "compatdata", appID, "pfx")
+		if st, err := os.Stat(candidate); err == nil && st.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no Proton prefix found for appid %s in Steam libraries", appID)
+}
@@ -0,0 +1,213 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/proton"
+)
+
+// Sources a launch target can be derived from.
+const (
+	sourceOwn     = "own"
+	sourceSteam   = "steam"
+	sourceUmu     = "umu"
+	sourceLutris  = "lutris"
+	sourceHeroic  = "heroic"
+	sourceBottles = "bottles"
+	sourceCustom  = "custom"
+)
+
+// launchTarget is the Wine environment WeMod is started in.
+type launchTarget struct {
+	Source string
+	// GameID selects per-game config overrides.
+	GameID string
+	Prefix string
+	// GamePrefix is false when WeMod runs in its own prefix. Game prefixes get
+	// runtime verbs installed and WeMod data synced into them.
+	GamePrefix bool
+	ProtonPath string
+	// Proton describes the Proton build at ProtonPath, if any.
+	Proton *proton.Info
+	// Wine is the wine binary to use; empty means system wine.
+	Wine string
+}
+
+// launchOptions are the launcher flags accepted in front of the game command.
+type launchOptions struct {
+	Lutris  string
+	Heroic  string
+	Bottles string
+	// Prefix and Wine select a plain Wine prefix and wine build explicitly.
+	Prefix string
+	Wine   string
+	// Game selects the [games.<id>] config section.
+	Game string
+}
+
+// splitLaunchOptions consumes leading launcher flags and returns the
+// remaining arguments. Parsing stops at the first unknown argument or "--".
+func splitLaunchOptions(args []string) (launchOptions, []string, error) {
+	var options launchOptions
+	targets := map[string]*string{
+		"--lutris":  &options.Lutris,
+		"--heroic":  &options.Heroic,
+		"--bottles": &options.Bottles,
+		"--prefix":  &options.Prefix,
+		"--wine":    &options.Wine,
+		"--game":    &options.Game,
+	}
+
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		target, ok := targets[name]
+		if !ok {
+			break
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return launchOptions{}, nil, fmt.Errorf("missing value for %s", name)
+			}
+			i++
+			value = args[i]
+		}
+		if strings.TrimSpace(value) == "" {
+			return launchOptions{}, nil, fmt.Errorf("empty value for %s", name)
+		}
+		*target = value
+	}
+
+	set := 0
+	for _, value := range []string{options.Lutris, options.Heroic, options.Bottles, options.Prefix + options.Wine} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return launchOptions{}, nil, fmt.Errorf("--lutris, --heroic, --bottles and --prefix/--wine are mutually exclusive")
+	}
+	return options, args[i:], nil
+}
+
+// resolveLaunchTarget derives the WeMod environment from an explicit game
+// launcher source or, without one, from the game command and Steam/umu env.
+func resolveLaunchTarget(cfg *config.Config, options launchOptions, gameCmd []string) (launchTarget, error) {
+	switch {
+	case options.Lutris != "":
+		return resolveLutrisTarget(options.Lutris)
+	case options.Heroic != "":
+		return resolveHeroicTarget(options.Heroic)
+	case options.Bottles != "":
+		return resolveBottlesTarget(options.Bottles)
+	}
+
+	gameID := options.Game
+	if gameID == "" {
+		gameID = resolveGameID()
+	}
+	prefix, wine := options.Prefix, options.Wine
+	if prefix == "" && wine == "" {
+		game := cfg.Game(gameID)
+		prefix, wine = game.Prefix, game.Wine
+	}
+	if prefix != "" || wine != "" {
+		return resolveCustomTarget(gameID, prefix, wine, gameCmd)
+	}
+
+	prefix, gamePrefix := resolveWeModPrefix(cfg, gameCmd)
+	target := launchTarget{Source: sourceOwn, Prefix: prefix}
+	if !gamePrefix {
+		return target, nil
+	}
+
+	command := inspectGameCommand(gameCmd)
+	target.Source = sourceSteam
+	if command.kind == commandUmu {
+		target.Source = sourceUmu
+	}
+	target.GameID = gameID
+	target.GamePrefix = true
+	if command.protonPath != "" {
+		target.setProton(command.protonPath)
+	}
+	return target, nil
+}
+
+// setProton inspects the Proton build at protonPath and uses its wine.
+func (t *launchTarget) setProton(protonPath string) {
+	info := proton.Inspect(protonPath)
+	t.ProtonPath = protonPath
+	t.Proton = &info
+	t.Wine = info.WineBinary
+}
+
+// resolveCustomTarget builds a target from an explicit prefix and wine build.
+// Without a prefix WINEPREFIX is used; without a wine binary the wine that
+// starts the game command is reused, so "--prefix <dir> -- wine game.exe"
+// picks up custom builds such as wine-ge or wine-tkg.
+func resolveCustomTarget(gameID, prefix, wine string, gameCmd []string) (launchTarget, error) {
+	prefix = expandHome(prefix)
+	if prefix == "" {
+		prefix = strings.TrimSpace(os.Getenv("WINEPREFIX"))
+	}
+	if prefix == "" {
+		return launchTarget{}, errors.New("--wine requires --prefix (or WINEPREFIX)")
+	}
+	if !filepath.IsAbs(prefix) {
+		abs, err := filepath.Abs(prefix)
+		if err != nil {
+			return launchTarget{}, fmt.Errorf("resolve prefix path: %w", err)
+		}
+		prefix = abs
+	}
+
+	target := launchTarget{Source: sourceCustom, GameID: gameID, Prefix: prefix, GamePrefix: true}
+	if wine = expandHome(wine); wine != "" {
+		resolved, err := resolveWineBinary(wine)
+		if err != nil {
+			return launchTarget{}, err
+		}
+		target.Wine = resolved
+	} else if len(gameCmd) > 0 && isWineBinary(gameCmd[0]) {
+		if resolved, err := resolveWineBinary(gameCmd[0]); err == nil {
+			target.Wine = resolved
+		}
+	}
+	return target, nil
+}
+
+// resolveWineBinary accepts a wine binary, a wine build directory containing
+// bin/wine, or a command name on PATH.
+func resolveWineBinary(wine string) (string, error) {
+	if !strings.ContainsRune(wine, filepath.Separator) {
+		path, err := exec.LookPath(wine)
+		if err != nil {
+			return "", fmt.Errorf("wine binary %q not found in PATH", wine)
+		}
+		return path, nil
+	}
+	if st, err := os.Stat(wine); err == nil && st.IsDir() {
+		wine = filepath.Join(wine, "bin", "wine")
+	}
+	if !isExecutable(wine) {
+		return "", fmt.Errorf("wine binary %s is not executable", wine)
+	}
+	return filepath.Abs(wine)
+}
+
+func isWineBinary(arg string) bool {
+	base := filepath.Base(arg)
+	return base == "wine" || base == "wine64"
+}
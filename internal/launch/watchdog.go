@@ -0,0 +1,86 @@
+package launch
+
+import (
+	"context"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+)
+
+// watchWeMod restarts WeMod when it exits while ctx (the game) is still
+// running. Restarts and stops requested over the control socket are left
+// alone. Failed restarts are retried with the next backoff step. It returns
+// once ctx is done or the restart attempts are used up.
+func (s *launchSession) watchWeMod(ctx context.Context, watchdog config.WatchdogConfig) {
+	logger := s.logger.WithComponent("launch.watchdog")
+	logger.Info("WeMod watchdog active (max %d restarts)", watchdog.MaxRestarts)
+	attempts := 0
+	for {
+		s.mu.Lock()
+		wemodProc, changed := s.wemod, s.changed
+		idle := wemodProc == nil || s.restarting || s.stopped
+		s.mu.Unlock()
+
+		var done <-chan struct{}
+		if !idle {
+			done = wemodProc.done
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			continue
+		case <-done:
+		}
+
+		s.mu.Lock()
+		unexpected := s.wemod == wemodProc && !s.restarting && !s.stopped
+		s.mu.Unlock()
+		if !unexpected {
+			continue
+		}
+		logger.Warn("WeMod exited unexpectedly (pid=%d): %v", wemodProc.pid(), wemodProc.err)
+		// Electron helpers can outlive the main process; clear them first.
+		if err := stopWeModProcessGroup(wemodProc.pid()); err != nil {
+			logger.Warn("failed to stop remaining WeMod processes: %v", err)
+		}
+
+		// A failed restart leaves no WeMod process; retry until one starts,
+		// the attempts are used up or WeMod is restarted or stopped on request.
+		current := wemodProc
+		for {
+			if attempts >= watchdog.MaxRestarts {
+				logger.Warn("WeMod watchdog giving up after %d restart attempts", attempts)
+				userNotice("WeMod crashed again; not restarting it (limit of %d restarts reached).", watchdog.MaxRestarts)
+				return
+			}
+			attempts++
+			delay := watchdog.Backoff(attempts)
+			logger.Info("restarting WeMod in %s (attempt %d/%d)", delay, attempts, watchdog.MaxRestarts)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			s.mu.Lock()
+			unexpected = s.wemod == current && !s.restarting && !s.stopped
+			s.mu.Unlock()
+			if !unexpected {
+				logger.Info("WeMod was restarted or stopped on request; skipping watchdog restart")
+				break
+			}
+
+			userNotice("WeMod crashed, restarting it (%d/%d) ...", attempts, watchdog.MaxRestarts)
+			message, err := s.RestartWeMod(ctx)
+			if err != nil {
+				logger.Warn("WeMod watchdog restart failed: %v", err)
+				current = nil
+				continue
+			}
+			// The status shows restarts that started WeMod.
+			s.update(func() { s.restarts++ })
+			logger.Info("%s", message)
+			break
+		}
+	}
+}
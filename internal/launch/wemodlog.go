@@ -0,0 +1,110 @@
+package launch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+)
+
+// WeMod logs are named wemod-<start time>-<session id>.log, so sorting by
+// name sorts them by age.
+const (
+	weModLogPrefix = "wemod-"
+	weModLogSuffix = ".log"
+)
+
+// openWeModLog creates the WeMod log of a launch started at started and
+// deletes the oldest logs beyond wemod.log.keep.
+func openWeModLog(cfg *config.Config, started time.Time, sessionID string) (*os.File, error) {
+	dir := cfg.WeModLogDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create WeMod log dir: %w", err)
+	}
+	name := fmt.Sprintf("%s%s-%s%s", weModLogPrefix, started.Format("20060102-150405"), sessionID, weModLogSuffix)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open WeMod log: %w", err)
+	}
+	if err := pruneWeModLogs(dir, cfg.WeMod.Log.Keep, name); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// pruneWeModLogs keeps the newest keep WeMod logs in dir; current is never
+// deleted. Logs still written by other launches stay readable through their
+// open file handles.
+func pruneWeModLogs(dir string, keep int, current string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read WeMod log dir: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && name != current && strings.HasPrefix(name, weModLogPrefix) && strings.HasSuffix(name, weModLogSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	keep = max(keep, 1) - 1
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove old WeMod log: %w", err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// weModLogCheckInterval is how often the size of the WeMod log is checked.
+const weModLogCheckInterval = 5 * time.Second
+
+// truncateWeModLog shrinks the WeMod log once it exceeds maxSize. It
+// keeps the first half, which holds the start headers and startup errors,
+// and marks the cut; WeMod keeps appending after it. It reports whether the
+// log was truncated.
+func truncateWeModLog(file *os.File, maxSize int64) (bool, error) {
+	st, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("stat WeMod log: %w", err)
+	}
+	if maxSize <= 0 || st.Size() <= maxSize {
+		return false, nil
+	}
+	head := make([]byte, maxSize/2)
+	reader, err := os.Open(file.Name())
+	if err != nil {
+		return false, fmt.Errorf("open WeMod log: %w", err)
+	}
+	n, err := io.ReadFull(reader, head)
+	_ = reader.Close()
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, fmt.Errorf("read WeMod log: %w", err)
+	}
+	cut := int64(bytes.LastIndexByte(head[:n], '\n') + 1)
+	if err := file.Truncate(cut); err != nil {
+		return false, fmt.Errorf("truncate WeMod log: %w", err)
+	}
+	fmt.Fprintf(file, "==== %s WeMod log reached %d MB, dropped %d bytes of output ====\n", time.Now().Format(time.RFC3339), maxSize>>20, st.Size()-cut)
+	return true, nil
+}
+
+// writeWeModLogHeader separates the output of consecutive WeMod starts of one
+// launch (profile fallback, restarts).
+func writeWeModLogHeader(file *os.File, sessionID, profile string, args []string, env map[string]string) {
+	if file == nil {
+		return
+	}
+	fmt.Fprintf(file, "==== %s WeMod start (session=%s profile=%s) args=%q WINEDEBUG=%q ====\n", time.Now().Format(time.RFC3339), sessionID, profile, logging.RedactArgs(args), env["WINEDEBUG"])
+}
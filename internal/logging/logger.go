@@ -1,11 +1,14 @@
 package logging
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -16,11 +19,38 @@ import (
 type Logger struct {
 	mu    *sync.Mutex
 	level int
-	l     *log.Logger
-	file  *os.File
-	name  string
+	// l writes text lines to stdout and, with the text format, to the log
+	// file. With the JSON format the log file is written by jsonLog.
+	l       *log.Logger
+	jsonLog *slog.Logger
+	file    io.Closer
+	name    string
+	session string
+	redact  *Redactor
+	// fields are structured key/value pairs added with With; only the JSON
+	// output carries them.
+	fields []any
 }
 
+// SessionIDEnv passes the session ID of a launcher run to the processes it
+// starts (hooks, WeMod, the game).
+const SessionIDEnv = "WEMOD_SESSION_ID"
+
+// NewSessionID returns a random ID for one launcher run.
+func NewSessionID() string {
+	var id [4]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// Log file formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 const (
 	levelDebug = iota
 	levelInfo
@@ -48,29 +78,72 @@ func New(cfg *config.Config) (*Logger, error) {
 	if err != nil {
 		return nil, err
 	}
+	format, err := NormalizeFormat(cfg.General.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := NewRedactor(cfg.Redact.Keys, cfg.Redact.Patterns)
+	if err != nil {
+		return nil, err
+	}
 	var writers []io.Writer
 	writers = append(writers, os.Stdout)
 
-	var file *os.File
+	logger := &Logger{
+		mu:      &sync.Mutex{},
+		level:   level,
+		name:    "root",
+		session: NewSessionID(),
+		redact:  redactor,
+	}
 	if cfg.General.LogFile != "" {
-		if err := os.MkdirAll(filepath.Dir(cfg.General.LogFile), 0o755); err != nil {
-			return nil, fmt.Errorf("create log dir: %w", err)
-		}
-		f, err := os.OpenFile(cfg.General.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		file, err := openRotatingFile(cfg.General.LogFile, rotateOptions(cfg))
 		if err != nil {
-			return nil, fmt.Errorf("open log file: %w", err)
+			return nil, err
+		}
+		logger.file = file
+		if format == FormatJSON {
+			logger.jsonLog = newJSONLogger(file).With("session_id", logger.session)
+		} else {
+			writers = append(writers, file)
 		}
-		file = f
-		writers = append(writers, file)
 	}
+	// The session ID follows the timestamp, so interleaved lines of
+	// concurrent launcher processes can be told apart.
+	logger.l = log.New(io.MultiWriter(writers...), "["+logger.session+"] ", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lmsgprefix)
+	return logger, nil
+}
 
-	return &Logger{
-		mu:    &sync.Mutex{},
-		level: level,
-		l:     log.New(io.MultiWriter(writers...), "", log.Ldate|log.Ltime|log.Lmicroseconds),
-		file:  file,
-		name:  "root",
-	}, nil
+// newJSONLogger writes one JSON object per line with the keys timestamp,
+// level, message, component and the structured fields.
+func newJSONLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		// Levels are filtered by Logger; the banner is logged at every level.
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return attr
+			}
+			switch attr.Key {
+			case slog.TimeKey:
+				attr.Key = "timestamp"
+			case slog.LevelKey:
+				attr.Value = slog.StringValue(strings.ToLower(attr.Value.String()))
+			case slog.MessageKey:
+				attr.Key = "message"
+			}
+			return attr
+		},
+	}))
+}
+
+func rotateOptions(cfg *config.Config) RotateOptions {
+	return RotateOptions{
+		MaxSize:  int64(max(cfg.General.LogMaxSizeMB, 0)) << 20,
+		Keep:     cfg.General.LogKeep,
+		MaxAge:   time.Duration(max(cfg.General.LogMaxAgeDays, 0)) * 24 * time.Hour,
+		Compress: cfg.General.LogCompress,
+	}
 }
 
 func ParseLevel(value string) (int, error) {
@@ -85,6 +158,17 @@ func ParseLevel(value string) (int, error) {
 	return level, nil
 }
 
+// NormalizeFormat validates a log format; empty selects the text format.
+func NormalizeFormat(value string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(value)); normalized {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid log format: %q (valid: %s|%s)", value, FormatText, FormatJSON)
+}
+
 func NormalizeLevel(value string) (string, error) {
 	level, err := ParseLevel(value)
 	if err != nil {
@@ -107,6 +191,14 @@ func (l *Logger) LevelName() string {
 	return "unknown"
 }
 
+// SessionID returns the ID of this launcher run.
+func (l *Logger) SessionID() string {
+	if l == nil {
+		return ""
+	}
+	return l.session
+}
+
 func (l *Logger) WithComponent(name string) *Logger {
 	if l == nil {
 		return nil
@@ -115,13 +207,54 @@ func (l *Logger) WithComponent(name string) *Logger {
 	if normalized == "" {
 		normalized = l.name
 	}
-	return &Logger{
-		mu:    l.mu,
-		level: l.level,
-		l:     l.l,
-		file:  l.file,
-		name:  normalized,
+	clone := *l
+	clone.name = normalized
+	return &clone
+}
+
+// WithOutput returns a logger that writes text lines only to w, e.g. to
+// capture the output of a command. Level, session ID and redaction are kept.
+func (l *Logger) WithOutput(w io.Writer) *Logger {
+	if l == nil {
+		return nil
+	}
+	clone := *l
+	clone.l = log.New(w, l.l.Prefix(), l.l.Flags())
+	clone.jsonLog = nil
+	clone.file = nil
+	return &clone
+}
+
+// With returns a logger that adds the given key/value pairs to every JSON
+// record, like slog.Logger.With.
+func (l *Logger) With(args ...any) *Logger {
+	if l == nil {
+		return nil
+	}
+	clone := *l
+	clone.fields = append(append([]any{}, l.fields...), args...)
+	return &clone
+}
+
+var slogLevels = map[int]slog.Level{
+	levelDebug: slog.LevelDebug,
+	levelInfo:  slog.LevelInfo,
+	levelWarn:  slog.LevelWarn,
+	levelError: slog.LevelError,
+}
+
+// logJSON writes a record to the JSON log file, if any. l.mu is held.
+func (l *Logger) logJSON(level slog.Level, msg string, args ...any) {
+	if l.jsonLog == nil {
+		return
+	}
+	attrs := append(append([]any{"component", l.name}, l.fields...), args...)
+	for i, attr := range attrs {
+		if value, ok := attr.(string); ok && i%2 == 1 {
+			attrs[i] = l.redact.String(value)
+		}
 	}
+	l.jsonLog.Log(context.Background(), level, msg, attrs...)
 }
 
 func (l *Logger) logf(level int, tag string, format string, args ...any) {
@@ -133,7 +266,8 @@ func (l *Logger) logf(level int, tag string, format string, args ...any) {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	msg := fmt.Sprintf(format, args...)
+	msg := l.redact.String(fmt.Sprintf(format, args...))
+	l.logJSON(slogLevels[level], msg)
 	if strings.TrimSpace(l.name) != "" {
 		l.l.Printf("[%s] [%s] %s", tag, l.name, msg)
 		return
@@ -146,14 +280,15 @@ func (l *Logger) Info(format string, args ...any)  { l.logf(levelInfo, "INFO", f
 func (l *Logger) Warn(format string, args ...any)  { l.logf(levelWarn, "WARN", format, args...) }
 func (l *Logger) Error(format string, args ...any) { l.logf(levelError, "ERROR", format, args...) }
 
-// StartupBanner emits a clearly visible session-start block with a separator
-// and explicit timestamp. It is always logged regardless of configured level.
+// StartupBanner emits a clearly visible session-start block with a separator,
+// explicit timestamp and the session ID. It is always logged regardless of
+// configured level.
 func (l *Logger) StartupBanner(message string) {
 	if l == nil {
 		return
 	}
 
-	msg := strings.TrimSpace(message)
+	msg := l.redact.String(strings.TrimSpace(message))
 	if msg == "" {
 		msg = "application start"
 	}
@@ -164,15 +299,16 @@ func (l *Logger) StartupBanner(message string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.logJSON(slog.LevelInfo, msg, "event", "start")
 	if strings.TrimSpace(l.name) != "" {
 		l.l.Printf("[START] [%s] %s", l.name, sep)
-		l.l.Printf("[START] [%s] %s | %s", l.name, msg, ts)
+		l.l.Printf("[START] [%s] %s | %s | session %s", l.name, msg, ts, l.session)
 		l.l.Printf("[START] [%s] %s", l.name, sep)
 		return
 	}
 
 	l.l.Printf("[START] %s", sep)
-	l.l.Printf("[START] %s | %s", msg, ts)
+	l.l.Printf("[START] %s | %s | session %s", msg, ts, l.session)
 	l.l.Printf("[START] %s", sep)
 }
 
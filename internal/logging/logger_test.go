@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+)
+
+func TestNew_JSONFormat(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "info"
+	cfg.General.LogFormat = "JSON"
+	cfg.General.LogFile = filepath.Join(t.TempDir(), "wemod-launcher.log")
+	logger, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	logger.StartupBanner("session start")
+	launch := logger.WithComponent("launch").With("game_id", "1245620")
+	launch.Debug("filtered")
+	launch.WithComponent("launch.session").Warn("WeMod exited (pid=%d)", 42)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.General.LogFile)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got:\n%s", data)
+	}
+	var records []map[string]any
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, record["timestamp"].(string)); err != nil {
+			t.Fatalf("invalid timestamp in %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if records[0]["message"] != "session start" || records[0]["event"] != "start" || records[0]["component"] != "root" || records[0]["session_id"] != logger.SessionID() {
+		t.Fatalf("unexpected banner record: %v", records[0])
+	}
+	want := map[string]any{"level": "warn", "component": "launch.session", "message": "WeMod exited (pid=42)", "game_id": "1245620", "session_id": logger.SessionID()}
+	for key, value := range want {
+		if records[1][key] != value {
+			t.Fatalf("record %v: %s = %v, want %v", records[1], key, records[1][key], value)
+		}
+	}
+}
+
+func TestNew_TextFormatPrefixesSessionID(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "debug"
+	cfg.General.LogFile = filepath.Join(t.TempDir(), "wemod-launcher.log")
+	logger, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	if len(logger.SessionID()) != 8 {
+		t.Fatalf("unexpected session id %q", logger.SessionID())
+	}
+	logger.StartupBanner("session start")
+	logger.WithComponent("launch").Info("hello")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.General.LogFile)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[1], "| session "+logger.SessionID()) {
+		t.Fatalf("unexpected log:\n%s", data)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, " ["+logger.SessionID()+"] [") {
+			t.Fatalf("line without session id: %q", line)
+		}
+	}
+	if !strings.HasSuffix(lines[3], "["+logger.SessionID()+"] [INFO] [launch] hello") {
+		t.Fatalf("unexpected line: %q", lines[3])
+	}
+}
+
+func TestNew_RedactsMessagesAndFields(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "debug"
+	cfg.General.LogFormat = FormatJSON
+	cfg.General.LogFile = filepath.Join(t.TempDir(), "wemod-launcher.log")
+	cfg.Redact.Keys = []string{"steamgrid"}
+	logger, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	logger.With("game_command", "game.exe --token abc").Debug("raw args: %q", []string{"--steamgrid-key", "xyz"})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.General.LogFile)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if strings.Contains(string(data), "abc") || strings.Contains(string(data), "xyz") {
+		t.Fatalf("secret written to log: %s", data)
+	}
+	if !strings.Contains(string(data), `"message":"raw args: [\"--steamgrid-key\" \"***\"]"`) {
+		t.Fatalf("unexpected log: %s", data)
+	}
+
+	cfg.Redact.Patterns = []string{"["}
+	if _, err := New(cfg); err == nil {
+		t.Fatal("expected error for invalid redact pattern")
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	for input, want := range map[string]string{"": FormatText, " Text ": FormatText, "json": FormatJSON} {
+		got, err := NormalizeFormat(input)
+		if err != nil || got != want {
+			t.Fatalf("NormalizeFormat(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := NormalizeFormat("xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
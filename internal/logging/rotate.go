@@ -0,0 +1,357 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotateOptions control rotation of the launcher log file.
+type RotateOptions struct {
+	// MaxSize rotates the file once it reaches this many bytes; 0 disables
+	// rotation.
+	MaxSize int64
+	// Keep is the number of rotated files kept.
+	Keep int
+	// MaxAge rotates the file when it is opened and its first line is older
+	// than this, and deletes rotated files older than this; 0 disables it.
+	MaxAge time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// textTimeFormat is the timestamp at the start of text log lines.
+const textTimeFormat = "2006/01/02 15:04:05.000000"
+
+// rotatedTimeFormat is the timestamp in rotated file names:
+// wemod-launcher-20261018-153000.000.log(.gz).
+const rotatedTimeFormat = "20060102-150405.000"
+
+// reopenInterval is how often a writer checks whether another launcher
+// process rotated the file.
+const reopenInterval = 5 * time.Second
+
+// rotatingFile is an append-only log file shared by concurrent launcher
+// processes. Rotation renames the file under an exclusive flock on it; the
+// other processes notice the rename and reopen the path.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	opts    RotateOptions
+	file    *os.File
+	size    int64
+	checked time.Time
+	now     func() time.Time
+}
+
+func openRotatingFile(path string, opts RotateOptions) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	r := &rotatingFile{path: path, opts: opts, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	// Rotation by age and retention also apply to logs that never reach the
+	// size limit.
+	var err error
+	if r.expired() {
+		err = r.rotate(0)
+	} else {
+		err = r.cleanup("")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+	}
+	return r, nil
+}
+
+// open opens the path and replaces r.file. The previous file is left to the
+// caller to close.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	st, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.file = file
+	r.size = st.Size()
+	r.checked = r.now()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Other processes may rotate by size or, at start, by age; follow them
+	// even when rotation is off in this one.
+	if r.now().Sub(r.checked) >= reopenInterval {
+		r.followRotation()
+	}
+	if r.full(r.size, len(p)) {
+		// A failed rotation must not stop logging; keep appending.
+		if err := r.rotate(len(p)); err != nil {
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// followRotation reopens the path when another process rotated the file and
+// refreshes the size, which includes writes of other processes.
+func (r *rotatingFile) followRotation() {
+	r.checked = r.now()
+	if !r.current() {
+		previous := r.file
+		if err := r.open(); err != nil {
+			fmt.Fprintf(os.Stderr, "reopen log file: %v\n", err)
+			return
+		}
+		_ = previous.Close()
+		return
+	}
+	if st, err := r.file.Stat(); err == nil {
+		r.size = st.Size()
+	}
+}
+
+// full reports whether writing incoming bytes to a file of the given size
+// exceeds the size limit.
+func (r *rotatingFile) full(size int64, incoming int) bool {
+	return r.opts.MaxSize > 0 && size > 0 && size+int64(incoming) > r.opts.MaxSize
+}
+
+// expired reports whether the open file was started more than MaxAge ago.
+func (r *rotatingFile) expired() bool {
+	if r.opts.MaxAge <= 0 || r.size == 0 {
+		return false
+	}
+	started, ok := firstLineTime(r.path)
+	if !ok {
+		st, err := r.file.Stat()
+		if err != nil {
+			return false
+		}
+		started = st.ModTime()
+	}
+	return r.now().Sub(started) > r.opts.MaxAge
+}
+
+// firstLineTime returns the timestamp of the first line of a text or JSON
+// log file.
+func firstLineTime(path string) (time.Time, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadSlice('\n')
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return time.Time{}, false
+	}
+	if len(line) > 0 && line[0] == '{' {
+		var record struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if json.Unmarshal(line, &record) == nil && !record.Timestamp.IsZero() {
+			return record.Timestamp, true
+		}
+		return time.Time{}, false
+	}
+	if len(line) < len(textTimeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(textTimeFormat, string(line[:len(textTimeFormat)]), time.Local)
+	return t, err == nil
+}
+
+// current reports whether the path still refers to the open file.
+func (r *rotatingFile) current() bool {
+	pathInfo, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	fileInfo, err := r.file.Stat()
+	return err == nil && os.SameFile(pathInfo, fileInfo)
+}
+
+// rotate renames the log file and opens a new one. The flock on the open
+// file serializes processes that reach the size limit at the same time: the
+// first renames the file, the others find the path replaced and reopen it.
+func (r *rotatingFile) rotate(incoming int) error {
+	locked := r.file
+	if err := syscall.Flock(int(locked.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("lock log file: %w", err)
+	}
+	defer func() {
+		_ = syscall.Flock(int(locked.Fd()), syscall.LOCK_UN)
+		if r.file != locked {
+			_ = locked.Close()
+		}
+	}()
+
+	if !r.current() {
+		return r.open()
+	}
+	st, err := r.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.size = st.Size()
+	if !r.full(r.size, incoming) && !r.expired() {
+		return nil
+	}
+	rotated := r.rotatedPath(r.now())
+	if err := os.Rename(r.path, rotated); err != nil {
+		return fmt.Errorf("rename log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	return r.cleanup(rotated)
+}
+
+func (r *rotatingFile) rotatedPath(t time.Time) string {
+	ext := filepath.Ext(r.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), t.Format(rotatedTimeFormat), ext)
+}
+
+// cleanup compresses and deletes rotated files. The newest one stays
+// uncompressed while it was just rotated or written recently: processes that
+// have not noticed the rotation yet may still write to it. A later cleanup,
+// e.g. when the next launcher starts, compresses it.
+func (r *rotatingFile) cleanup(newest string) error {
+	backups, err := rotatedFiles(r.path)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, backup := range backups {
+		expired := r.opts.MaxAge > 0 && r.now().Sub(backup.time) > r.opts.MaxAge
+		if i >= max(r.opts.Keep, 1) || expired {
+			if err := os.Remove(backup.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if r.opts.Compress && backup.path != newest && !strings.HasSuffix(backup.path, ".gz") && (i > 0 || !r.inUse(backup.path)) {
+			if err := compressFile(backup.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inUse reports whether path was written within two reopen intervals, i.e.
+// another process may not have followed its rotation yet.
+func (r *rotatingFile) inUse(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && r.now().Sub(st.ModTime()) < 2*reopenInterval
+}
+
+type rotatedFile struct {
+	path string
+	time time.Time
+}
+
+// rotatedFiles returns the rotated files of the log at path, newest first.
+func rotatedFiles(path string) ([]rotatedFile, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read log dir: %w", err)
+	}
+	var files []rotatedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(strings.TrimSuffix(name, ".gz"), prefix)
+		if !ok || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(rotatedTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(files, func(a, b int) bool { return files[a].time.After(files[b].time) })
+	return files, nil
+}
+
+// LogFiles returns the log file at path and its rotated files, oldest first.
+// Rotated files may be gzip compressed (".gz").
+func LogFiles(path string) ([]string, error) {
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(rotated)+1)
+	for i := len(rotated) - 1; i >= 0; i-- {
+		files = append(files, rotated[i].path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// compressFile replaces path with path.gz.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open rotated log: %w", err)
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.gz.tmp")
+	if err != nil {
+		return fmt.Errorf("create compressed log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compress rotated log: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compress rotated log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write compressed log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		return fmt.Errorf("replace rotated log: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove rotated log: %w", err)
+	}
+	return nil
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,227 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by one second per call.
+func fakeClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func openTestRotatingFile(t *testing.T, path string, opts RotateOptions, now func() time.Time) *rotatingFile {
+	t.Helper()
+	r, err := openRotatingFile(path, opts)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	r.now = now
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	var reader io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip %s: %v", path, err)
+		}
+		reader = zr
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile_RotatesKeepsAndCompresses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wemod-launcher.log")
+	r := openTestRotatingFile(t, path, RotateOptions{MaxSize: 20, Keep: 2, Compress: true}, fakeClock(time.Now()))
+
+	for _, line := range []string{"first line 0123456\n", "second line 012345\n", "third line 0123456\n", "fourth line 012345\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if got := readLog(t, path); got != "fourth line 012345\n" {
+		t.Fatalf("unexpected active log: %q", got)
+	}
+	backups, err := rotatedFiles(path)
+	if err != nil {
+		t.Fatalf("list rotated: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated logs, got %+v", backups)
+	}
+	if strings.HasSuffix(backups[0].path, ".gz") || !strings.HasSuffix(backups[1].path, ".gz") {
+		t.Fatalf("only older rotated logs should be compressed: %+v", backups)
+	}
+	if got := readLog(t, backups[0].path); got != "third line 0123456\n" {
+		t.Fatalf("unexpected newest rotated log: %q", got)
+	}
+	if got := readLog(t, backups[1].path); got != "second line 012345\n" {
+		t.Fatalf("unexpected compressed log: %q", got)
+	}
+}
+
+func TestRotatingFile_DeletesExpiredLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wemod-launcher.log")
+	now := time.Now()
+	old := strings.TrimSuffix(path, ".log") + "-" + now.Add(-48*time.Hour).Format(rotatedTimeFormat) + ".log.gz"
+	if err := os.WriteFile(old, nil, 0o644); err != nil {
+		t.Fatalf("write old log: %v", err)
+	}
+	r := openTestRotatingFile(t, path, RotateOptions{MaxSize: 10, Keep: 5, MaxAge: 24 * time.Hour}, fakeClock(now))
+	if _, err := r.Write([]byte("0123456789\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := r.Write([]byte("next\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expired log not deleted: %v", err)
+	}
+	if backups, _ := rotatedFiles(path); len(backups) != 1 {
+		t.Fatalf("expected one rotated log, got %+v", backups)
+	}
+}
+
+func TestRotatingFile_FollowsRotationByOtherProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wemod-launcher.log")
+	opts := RotateOptions{MaxSize: 30, Keep: 3}
+	clock := fakeClock(time.Now())
+	a := openTestRotatingFile(t, path, opts, clock)
+	b := openTestRotatingFile(t, path, opts, clock)
+
+	for _, write := range []struct {
+		r    *rotatingFile
+		line string
+	}{
+		{a, "a1 0123456789\n"},
+		{b, "b1 0123456789\n"},
+	} {
+		if _, err := write.r.Write([]byte(write.line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// b picks up the size including a's writes and rotates.
+	b.checked = b.checked.Add(-reopenInterval)
+	if _, err := b.Write([]byte("b2 0123456789\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// a notices the rotation once the reopen interval passed.
+	a.checked = a.checked.Add(-reopenInterval)
+	if _, err := a.Write([]byte("a2\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := readLog(t, path); got != "b2 0123456789\na2\n" {
+		t.Fatalf("unexpected active log: %q", got)
+	}
+	backups, err := rotatedFiles(path)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one rotated log, got %+v (%v)", backups, err)
+	}
+	if got := readLog(t, backups[0].path); got != "a1 0123456789\nb1 0123456789\n" {
+		t.Fatalf("unexpected rotated log: %q", got)
+	}
+}
+
+func TestOpenRotatingFile_RotatesByAgeWithoutSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wemod-launcher.log")
+	now := time.Now()
+	line := now.Add(-40*24*time.Hour).Format(textTimeFormat) + " [3f9a1c2e] [INFO] [cli] old\n"
+	if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	expired := strings.TrimSuffix(path, ".log") + "-" + now.Add(-60*24*time.Hour).Format(rotatedTimeFormat) + ".log"
+	if err := os.WriteFile(expired, nil, 0o644); err != nil {
+		t.Fatalf("write expired log: %v", err)
+	}
+
+	openTestRotatingFile(t, path, RotateOptions{Keep: 5, MaxAge: 30 * 24 * time.Hour}, time.Now)
+
+	if got := readLog(t, path); got != "" {
+		t.Fatalf("expired active log not rotated: %q", got)
+	}
+	backups, err := rotatedFiles(path)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one rotated log, got %+v (%v)", backups, err)
+	}
+	if got := readLog(t, backups[0].path); got != line {
+		t.Fatalf("unexpected rotated log: %q", got)
+	}
+}
+
+func TestOpenRotatingFile_CompressesIdleNewestBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wemod-launcher.log")
+	backup := strings.TrimSuffix(path, ".log") + "-" + time.Now().Add(-time.Hour).Format(rotatedTimeFormat) + ".log"
+	if err := os.WriteFile(backup, []byte("rotated\n"), 0o644); err != nil {
+		t.Fatalf("write rotated log: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(backup, old, old); err != nil {
+		t.Fatalf("set mtime: %v", err)
+	}
+
+	openTestRotatingFile(t, path, RotateOptions{MaxSize: 1 << 20, Keep: 1, Compress: true}, time.Now)
+
+	if got := readLog(t, backup+".gz"); got != "rotated\n" {
+		t.Fatalf("unexpected compressed log: %q", got)
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Fatalf("uncompressed log left behind: %v", err)
+	}
+}
+
+func TestRotatingFile_FollowsAgeRotationWithoutSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wemod-launcher.log")
+	opts := RotateOptions{Keep: 3, MaxAge: 24 * time.Hour}
+	clock := fakeClock(time.Now())
+	a := openTestRotatingFile(t, path, opts, clock)
+	line := time.Now().Add(-48*time.Hour).Format(textTimeFormat) + " [aaaaaaaa] [INFO] [cli] a1\n"
+	if _, err := a.Write([]byte(line)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// A second launcher starts and rotates the expired log.
+	b := openTestRotatingFile(t, path, opts, clock)
+	if _, err := b.Write([]byte("b1\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	a.checked = a.checked.Add(-reopenInterval)
+	if _, err := a.Write([]byte("a2\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := readLog(t, path); got != "b1\na2\n" {
+		t.Fatalf("unexpected active log: %q", got)
+	}
+	backups, err := rotatedFiles(path)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one rotated log, got %+v (%v)", backups, err)
+	}
+	if got := readLog(t, backups[0].path); got != line {
+		t.Fatalf("unexpected rotated log: %q", got)
+	}
+}
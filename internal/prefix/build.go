@@ -7,6 +7,7 @@ import (
 
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/prefixlock"
 	process "github.com/NichSchlagen/wemod-proton-launcher-go/internal/runtime"
 )
 
@@ -15,6 +16,13 @@ func Build(ctx context.Context, cfg *config.Config, logger *logging.Logger) erro
 	logger.Info("prefix build started")
 	logger.Debug("target prefix dir: %s", cfg.Paths.PrefixDir)
 
+	lock, err := prefixlock.Own(ctx, cfg, "prefix build", false)
+	if err != nil {
+		logger.Error("prefix build failed: %v", err)
+		return err
+	}
+	defer lock.Release()
+
 	if err := os.MkdirAll(cfg.Paths.PrefixDir, 0o755); err != nil {
 		logger.Error("failed creating prefix dir %s: %v", cfg.Paths.PrefixDir, err)
 		return fmt.Errorf("create prefix dir: %w", err)
@@ -23,7 +31,8 @@ func Build(ctx context.Context, cfg *config.Config, logger *logging.Logger) erro
 	env := map[string]string{
 		"WINEPREFIX": cfg.Paths.PrefixDir,
 	}
-	verbs := []string{"corefonts", "dotnet48"}
+	verbs := cfg.RuntimeVerbs("")
+	logger.Debug("winetricks verbs: %v", verbs)
 	for _, verb := range verbs {
 		logger.Info("installing winetricks %s", verb)
 		if err := process.Run(ctx, logger, "winetricks", []string{"-q", verb}, env); err != nil {
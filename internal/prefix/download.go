@@ -14,6 +14,7 @@ import (
 
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
 	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/prefixlock"
 )
 
 const defaultPrefixRepoAPI = "https://api.github.com/repos/NichSchlagen/wemod-prefix/releases/latest"
@@ -30,6 +31,13 @@ func Download(ctx context.Context, cfg *config.Config, logger *logging.Logger) e
 	logger.Info("prefix download workflow started")
 	logger.Debug("download dir=%s prefix dir=%s", cfg.Paths.DownloadDir, cfg.Paths.PrefixDir)
 
+	lock, err := prefixlock.Own(ctx, cfg, "prefix download", false)
+	if err != nil {
+		logger.Error("prefix download failed: %v", err)
+		return err
+	}
+	defer lock.Release()
+
 	if err := os.MkdirAll(cfg.Paths.DownloadDir, 0o755); err != nil {
 		logger.Error("failed creating download dir %s: %v", cfg.Paths.DownloadDir, err)
 		return fmt.Errorf("create download dir: %w", err)
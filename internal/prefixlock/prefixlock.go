@@ -0,0 +1,177 @@
+// Package prefixlock serializes launcher processes working on the same Wine
+// prefix with advisory flock(2) locks.
+package prefixlock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+// ErrBusy is returned when another launcher process holds the prefix lock.
+var ErrBusy = errors.New("prefix busy")
+
+// pollInterval is how often a waiting Acquire retries the lock.
+const pollInterval = 100 * time.Millisecond
+
+// Options configure Acquire.
+type Options struct {
+	// Shared allows other shared holders, e.g. several readers of the own
+	// prefix; exclusive locks are taken by anything that modifies a prefix.
+	Shared bool
+	// Wait is how long to wait for a busy lock; zero fails immediately.
+	Wait time.Duration
+	// Purpose describes the holder in "prefix busy" errors, e.g. "sync".
+	Purpose string
+}
+
+// Lock is a held prefix lock.
+type Lock struct {
+	file      *os.File
+	prefix    string
+	exclusive bool
+}
+
+// Dir returns the directory holding the lock files. Locks live outside the
+// prefixes so deleting a prefix (reset) does not drop its lock.
+func Dir() string {
+	return filepath.Join(registry.RuntimeDir(), "locks")
+}
+
+// Path returns the lock file of a prefix.
+func Path(prefix string) string {
+	prefix = normalize(prefix)
+	sum := sha256.Sum256([]byte(prefix))
+	return filepath.Join(Dir(), filepath.Base(prefix)+"-"+hex.EncodeToString(sum[:8])+".lock")
+}
+
+// Acquire locks prefix. A busy lock is retried until opts.Wait has passed or
+// ctx is done; the error then wraps ErrBusy and names the holder.
+func Acquire(ctx context.Context, prefix string, opts Options) (*Lock, error) {
+	if err := os.MkdirAll(Dir(), 0o700); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	path := Path(prefix)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open prefix lock: %w", err)
+	}
+
+	how := syscall.LOCK_EX
+	if opts.Shared {
+		how = syscall.LOCK_SH
+	}
+	deadline := time.Now().Add(opts.Wait)
+	for {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("lock prefix %s: %w", prefix, err)
+		}
+		if !time.Now().Before(deadline) {
+			holder := readHolder(path)
+			file.Close()
+			return nil, busyError(prefix, holder, opts.Wait)
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, fmt.Errorf("%w: %s: %w", ErrBusy, prefix, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+
+	// Only an exclusive holder owns the holder line; shared holders would
+	// overwrite each other's.
+	if !opts.Shared {
+		writeHolder(file, opts.Purpose)
+	}
+	return &Lock{file: file, prefix: prefix, exclusive: !opts.Shared}, nil
+}
+
+// Own locks the own WeMod prefix of cfg, shared for launches running WeMod in
+// it and exclusively for commands that replace it.
+func Own(ctx context.Context, cfg *config.Config, purpose string, shared bool) (*Lock, error) {
+	return Acquire(ctx, cfg.Paths.PrefixDir, Options{Shared: shared, Wait: cfg.LockWait(), Purpose: purpose})
+}
+
+// Release unlocks the prefix. It is safe to call on a nil lock and more than
+// once.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	if l.exclusive {
+		// Drop the holder line so later shared holders are not reported as
+		// this one.
+		_ = l.file.Truncate(0)
+	}
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return fmt.Errorf("release prefix lock %s: %w", l.prefix, err)
+	}
+	return nil
+}
+
+func normalize(prefix string) string {
+	prefix = filepath.Clean(prefix)
+	if abs, err := filepath.Abs(prefix); err == nil {
+		prefix = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(prefix); err == nil {
+		prefix = resolved
+	}
+	return prefix
+}
+
+func writeHolder(file *os.File, purpose string) {
+	if purpose == "" {
+		purpose = "launcher"
+	}
+	if err := file.Truncate(0); err != nil {
+		return
+	}
+	_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+" "+purpose+"\n"), 0)
+}
+
+func readHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	pid, purpose, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	if pid == "" {
+		return ""
+	}
+	// The line of a crashed exclusive holder outlives it.
+	if n, err := strconv.Atoi(pid); err != nil || syscall.Kill(n, 0) == syscall.ESRCH {
+		return ""
+	}
+	return fmt.Sprintf("pid %s, %s", pid, purpose)
+}
+
+func busyError(prefix, holder string, waited time.Duration) error {
+	message := fmt.Sprintf("%s is in use by another launcher", prefix)
+	if holder != "" {
+		message += " (" + holder + ")"
+	}
+	if waited > 0 {
+		return fmt.Errorf("%w: %s; gave up after %s", ErrBusy, message, waited)
+	}
+	return fmt.Errorf("%w: %s; retry later or pass --lock-wait <seconds>", ErrBusy, message)
+}
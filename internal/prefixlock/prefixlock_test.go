@@ -0,0 +1,165 @@
+package prefixlock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcquire_ExclusiveIsBusy(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := filepath.Join(t.TempDir(), "pfx")
+
+	lock, err := Acquire(context.Background(), prefix, Options{Purpose: "reset"})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	_, err = Acquire(context.Background(), prefix, Options{Purpose: "sync"})
+	if !errors.Is(err, ErrBusy) || !strings.Contains(err.Error(), "reset") {
+		t.Fatalf("expected busy error naming the holder, got %v", err)
+	}
+	if _, err := Acquire(context.Background(), prefix, Options{Shared: true}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("shared lock must wait for the exclusive holder, got %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("second release: %v", err)
+	}
+
+	other, err := Acquire(context.Background(), prefix, Options{})
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	other.Release()
+}
+
+func TestAcquire_SharedLocksCoexist(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := filepath.Join(t.TempDir(), "pfx")
+
+	first, err := Acquire(context.Background(), prefix, Options{Shared: true})
+	if err != nil {
+		t.Fatalf("acquire first: %v", err)
+	}
+	defer first.Release()
+	second, err := Acquire(context.Background(), prefix, Options{Shared: true})
+	if err != nil {
+		t.Fatalf("acquire second: %v", err)
+	}
+	defer second.Release()
+	if _, err := Acquire(context.Background(), prefix, Options{}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("exclusive lock must wait for shared holders, got %v", err)
+	}
+}
+
+func TestAcquire_SharedHoldersDoNotReportStaleHolder(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := filepath.Join(t.TempDir(), "pfx")
+
+	reset, err := Acquire(context.Background(), prefix, Options{Purpose: "reset"})
+	if err != nil {
+		t.Fatalf("acquire exclusive: %v", err)
+	}
+	reset.Release()
+	first, err := Acquire(context.Background(), prefix, Options{Shared: true, Purpose: "launch"})
+	if err != nil {
+		t.Fatalf("acquire first shared: %v", err)
+	}
+	defer first.Release()
+	second, err := Acquire(context.Background(), prefix, Options{Shared: true, Purpose: "status"})
+	if err != nil {
+		t.Fatalf("acquire second shared: %v", err)
+	}
+	second.Release()
+
+	_, err = Acquire(context.Background(), prefix, Options{Purpose: "sync"})
+	if !errors.Is(err, ErrBusy) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+	for _, stale := range []string{"reset", "status"} {
+		if strings.Contains(err.Error(), stale) {
+			t.Fatalf("busy error names %q, which no longer holds the lock: %v", stale, err)
+		}
+	}
+}
+
+func TestAcquire_ParallelWaiters(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := filepath.Join(t.TempDir(), "pfx")
+
+	var inside, maxInside, done atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := Acquire(context.Background(), prefix, Options{Wait: 10 * time.Second})
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			n := inside.Add(1)
+			for {
+				current := maxInside.Load()
+				if n <= current || maxInside.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			inside.Add(-1)
+			done.Add(1)
+			lock.Release()
+		}()
+	}
+	wg.Wait()
+	if maxInside.Load() != 1 {
+		t.Fatalf("lock held by %d goroutines at once", maxInside.Load())
+	}
+	if done.Load() != 8 {
+		t.Fatalf("only %d of 8 goroutines got the lock", done.Load())
+	}
+}
+
+func TestAcquire_WaitTimesOut(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	prefix := filepath.Join(t.TempDir(), "pfx")
+
+	lock, err := Acquire(context.Background(), prefix, Options{})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer lock.Release()
+
+	started := time.Now()
+	_, err = Acquire(context.Background(), prefix, Options{Wait: 300 * time.Millisecond})
+	if !errors.Is(err, ErrBusy) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 300*time.Millisecond {
+		t.Fatalf("gave up after %s, before the wait time", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Acquire(ctx, prefix, Options{Wait: time.Minute}); !errors.Is(err, ErrBusy) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled busy error, got %v", err)
+	}
+}
+
+func TestPath_NormalizesPrefix(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir := t.TempDir()
+	if Path(dir) != Path(dir+"/./") {
+		t.Fatal("equivalent prefix paths must share a lock")
+	}
+	if Path(dir) == Path(filepath.Join(dir, "other")) {
+		t.Fatal("different prefixes must not share a lock")
+	}
+}
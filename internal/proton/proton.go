@@ -0,0 +1,139 @@
+// Package proton inspects Proton installations: the version file,
+// compatibilitytool.vdf and the bundled wine build.
+package proton
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Flavors of Proton builds.
+const (
+	FlavorValve        = "valve"
+	FlavorExperimental = "experimental"
+	FlavorGE           = "ge"
+	FlavorCustom       = "custom"
+	FlavorUnknown      = "unknown"
+)
+
+// Layouts of the bundled wine build.
+const (
+	// LayoutFiles is files/bin/wine, used since Proton 5.0.
+	LayoutFiles = "files"
+	// LayoutDist is dist/bin/wine, used by Proton 4.11 and older.
+	LayoutDist = "dist"
+)
+
+var displayNamePattern = regexp.MustCompile(`"display_name"\s+"([^"]*)"`)
+
+// Info describes a Proton installation.
+type Info struct {
+	Dir    string `json:"dir"`
+	Script string `json:"script"`
+	// Name is the display name from compatibilitytool.vdf or the directory
+	// name for Steam-managed builds.
+	Name string `json:"name"`
+	// Version is the release name from the version file, e.g.
+	// "proton-9.0-3" or "GE-Proton9-20".
+	Version string `json:"version,omitempty"`
+	// BuildTime is the Unix timestamp from the version file.
+	BuildTime int64 `json:"build_time,omitempty"`
+	// VersionFile is the raw content of the version file.
+	VersionFile string `json:"version_file,omitempty"`
+	Flavor      string `json:"flavor"`
+	Layout      string `json:"layout,omitempty"`
+	WineBinary  string `json:"wine_binary,omitempty"`
+	WineServer  string `json:"wineserver,omitempty"`
+	// CompatTool is true for builds installed in compatibilitytools.d.
+	CompatTool bool `json:"compat_tool"`
+}
+
+// Inspect reads the Proton installation containing path, which may be the
+// proton script or its directory. Missing files leave fields empty.
+func Inspect(path string) Info {
+	path = filepath.Clean(path)
+	dir := path
+	if st, err := os.Stat(path); err != nil || !st.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	info := Info{Dir: dir, Script: filepath.Join(dir, "proton"), Name: filepath.Base(dir)}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "version")); err == nil {
+		info.VersionFile = strings.TrimSpace(string(data))
+		info.BuildTime, info.Version = parseVersionFile(info.VersionFile)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "compatibilitytool.vdf")); err == nil {
+		info.CompatTool = true
+		if match := displayNamePattern.FindSubmatch(data); match != nil && len(match[1]) > 0 {
+			info.Name = string(match[1])
+		}
+	}
+
+	for _, layout := range []string{LayoutFiles, LayoutDist} {
+		binDir := filepath.Join(dir, layout, "bin")
+		for _, name := range []string{"wine", "wine64"} {
+			if candidate := filepath.Join(binDir, name); isExecutable(candidate) {
+				info.Layout = layout
+				info.WineBinary = candidate
+				break
+			}
+		}
+		if info.WineBinary != "" {
+			if candidate := filepath.Join(binDir, "wineserver"); isExecutable(candidate) {
+				info.WineServer = candidate
+			}
+			break
+		}
+	}
+
+	info.Flavor = detectFlavor(info)
+	return info
+}
+
+// String returns a short human readable description.
+func (i Info) String() string {
+	version := i.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	return i.Name + " (" + version + ", " + i.Flavor + ")"
+}
+
+// parseVersionFile splits "<timestamp> <name>". Files without a timestamp
+// return the whole content as name.
+func parseVersionFile(content string) (int64, string) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, ""
+	}
+	if ts, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+		if len(fields) == 1 {
+			return ts, ""
+		}
+		return ts, fields[1]
+	}
+	return 0, fields[0]
+}
+
+func detectFlavor(info Info) string {
+	names := strings.ToLower(strings.Join([]string{info.Name, info.Version, filepath.Base(info.Dir)}, " "))
+	switch {
+	case strings.Contains(names, "ge-proton") || strings.Contains(names, "-ge-") || strings.HasSuffix(filepath.Base(info.Dir), "-GE"):
+		return FlavorGE
+	case strings.Contains(names, "experimental") || strings.Contains(names, "hotfix"):
+		return FlavorExperimental
+	case info.CompatTool:
+		return FlavorCustom
+	case strings.HasPrefix(strings.ToLower(info.Version), "proton-") || strings.HasPrefix(filepath.Base(info.Dir), "Proton "):
+		return FlavorValve
+	}
+	return FlavorUnknown
+}
+
+func isExecutable(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir() && st.Mode()&0o111 != 0
+}
@@ -0,0 +1,88 @@
+package proton
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestInspect_ValveProton(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Proton 9.0 (Beta)")
+	writeFile(t, filepath.Join(dir, "proton"), "#!/usr/bin/env python3\n", 0o755)
+	writeFile(t, filepath.Join(dir, "version"), "1712345678 proton-9.0-3\n", 0o644)
+	writeFile(t, filepath.Join(dir, "files", "bin", "wine"), "", 0o755)
+	writeFile(t, filepath.Join(dir, "files", "bin", "wineserver"), "", 0o755)
+
+	info := Inspect(filepath.Join(dir, "proton"))
+	if info.Flavor != FlavorValve || info.Version != "proton-9.0-3" || info.BuildTime != 1712345678 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.Layout != LayoutFiles || info.WineBinary != filepath.Join(dir, "files", "bin", "wine") || info.WineServer == "" {
+		t.Fatalf("unexpected wine layout: %+v", info)
+	}
+	if info.Name != "Proton 9.0 (Beta)" || info.CompatTool {
+		t.Fatalf("unexpected name: %+v", info)
+	}
+}
+
+func TestInspect_GEProtonFromCompatTool(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "GE-Proton9-20")
+	writeFile(t, filepath.Join(dir, "version"), "1718000000 GE-Proton9-20\n", 0o644)
+	writeFile(t, filepath.Join(dir, "compatibilitytool.vdf"), `"compatibilitytools"
+{
+  "compat_tools"
+  {
+    "GE-Proton9-20" // Internal name of this tool
+    {
+      "install_path" "."
+      "display_name" "GE-Proton9-20"
+      "from_oslist"  "windows"
+      "to_oslist"    "linux"
+    }
+  }
+}
+`, 0o644)
+	writeFile(t, filepath.Join(dir, "files", "bin", "wine64"), "", 0o755)
+
+	info := Inspect(dir)
+	if info.Flavor != FlavorGE || !info.CompatTool || info.Name != "GE-Proton9-20" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.WineBinary != filepath.Join(dir, "files", "bin", "wine64") {
+		t.Fatalf("unexpected wine binary: %s", info.WineBinary)
+	}
+}
+
+func TestInspect_ExperimentalAndDistLayout(t *testing.T) {
+	root := t.TempDir()
+	experimental := filepath.Join(root, "Proton - Experimental")
+	writeFile(t, filepath.Join(experimental, "version"), "1720000000 experimental-9.0-20240710\n", 0o644)
+	if info := Inspect(filepath.Join(experimental, "proton")); info.Flavor != FlavorExperimental {
+		t.Fatalf("expected experimental flavor, got %+v", info)
+	}
+
+	legacy := filepath.Join(root, "Proton 4.11")
+	writeFile(t, filepath.Join(legacy, "version"), "1570000000 proton-4.11-13\n", 0o644)
+	writeFile(t, filepath.Join(legacy, "dist", "bin", "wine"), "", 0o755)
+	info := Inspect(filepath.Join(legacy, "proton"))
+	if info.Layout != LayoutDist || info.WineBinary != filepath.Join(legacy, "dist", "bin", "wine") || info.Flavor != FlavorValve {
+		t.Fatalf("unexpected legacy info: %+v", info)
+	}
+}
+
+func TestInspect_MissingInstall(t *testing.T) {
+	info := Inspect("/nonexistent/Proton/proton")
+	if info.Flavor != FlavorUnknown || info.WineBinary != "" || info.Version != "" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
@@ -0,0 +1,144 @@
+// Package registry keeps one JSON file per running launch under
+// $XDG_RUNTIME_DIR so other launcher invocations can list active sessions.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session describes a running launch.
+type Session struct {
+	PID        int       `json:"pid"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Executable string    `json:"executable,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	GameID     string    `json:"game_id,omitempty"`
+	Source     string    `json:"source"`
+	Prefix     string    `json:"prefix"`
+	// Proton describes the Proton build; ProtonPath is its proton script.
+	Proton       string   `json:"proton,omitempty"`
+	ProtonPath   string   `json:"proton_path,omitempty"`
+	Wine         string   `json:"wine,omitempty"`
+	GameCommand  []string `json:"game_command,omitempty"`
+	GamePID      int      `json:"game_pid,omitempty"`
+	WeModPID     int      `json:"wemod_pid,omitempty"`
+	WeModProfile string   `json:"wemod_profile,omitempty"`
+	WeModLog     string   `json:"wemod_log,omitempty"`
+	// WeModRestarts counts restarts by the WeMod watchdog.
+	WeModRestarts int `json:"wemod_restarts,omitempty"`
+}
+
+// RuntimeDir returns the directory for sockets and session files:
+// $XDG_RUNTIME_DIR/wemod-launcher, or a per-user directory in the temp dir.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "wemod-launcher")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wemod-launcher-%d", os.Getuid()))
+}
+
+// Dir returns the directory holding the session files.
+func Dir() string {
+	return filepath.Join(RuntimeDir(), "sessions")
+}
+
+func sessionPath(pid int) string {
+	return filepath.Join(Dir(), strconv.Itoa(pid)+".json")
+}
+
+// Write stores or replaces the session file of session.PID.
+func Write(session Session) error {
+	if err := os.MkdirAll(Dir(), 0o700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+	path := sessionPath(session.PID)
+	tmp, err := os.CreateTemp(Dir(), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create session temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace session: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the session file of pid.
+func Remove(pid int) error {
+	if err := os.Remove(sessionPath(pid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove session: %w", err)
+	}
+	return nil
+}
+
+// List returns the sessions of running launchers, oldest first. Files of
+// launchers that are gone are removed.
+func List() ([]Session, error) {
+	entries, err := os.ReadDir(Dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read session dir: %w", err)
+	}
+	var sessions []Session
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(Dir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil || !Alive(session.PID, session.Executable) {
+			_ = os.Remove(path)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(a, b int) bool {
+		return sessions[a].StartedAt.Before(sessions[b].StartedAt)
+	})
+	return sessions, nil
+}
+
+// Alive reports whether pid is running. With executable set, a process
+// running another binary under a reused pid does not count.
+func Alive(pid int, executable string) bool {
+	if pid <= 0 {
+		return false
+	}
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+	if _, err := os.Stat(procDir); err != nil {
+		return false
+	}
+	if executable == "" {
+		return true
+	}
+	exe, err := os.Readlink(filepath.Join(procDir, "exe"))
+	if err != nil {
+		return true
+	}
+	return strings.TrimSuffix(exe, " (deleted)") == executable
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestList_RemovesStaleSessions(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("resolve executable: %v", err)
+	}
+	live := Session{PID: os.Getpid(), Executable: executable, StartedAt: time.Now(), Source: "steam", Prefix: "/tmp/pfx", WeModPID: 42}
+	// PIDs above the kernel maximum (4194304) can never be running.
+	gone := Session{PID: 99999999, StartedAt: time.Now().Add(-time.Hour), Source: "own"}
+	reused := Session{PID: os.Getppid(), Executable: "/nonexistent/wemod-launcher", StartedAt: time.Now()}
+	for _, session := range []Session{live, gone, reused} {
+		if err := Write(session); err != nil {
+			t.Fatalf("write session: %v", err)
+		}
+	}
+
+	sessions, err := List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].PID != live.PID || sessions[0].WeModPID != 42 {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	for _, pid := range []int{gone.PID, reused.PID} {
+		if _, err := os.Stat(sessionPath(pid)); !os.IsNotExist(err) {
+			t.Fatalf("stale session %d not removed: %v", pid, err)
+		}
+	}
+
+	if err := Remove(live.PID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if entries, _ := os.ReadDir(Dir()); len(entries) != 0 {
+		t.Fatalf("session dir not empty: %v", entries)
+	}
+	if filepath.Dir(Dir()) != RuntimeDir() {
+		t.Fatalf("session dir outside runtime dir: %s", Dir())
+	}
+}
+
+func TestWrite_ConcurrentWritersLeaveValidFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(restarts int) {
+			defer wg.Done()
+			if err := Write(Session{PID: 4242, Source: "steam", WeModRestarts: restarts}); err != nil {
+				t.Errorf("write session: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(sessionPath(4242))
+	if err != nil {
+		t.Fatalf("read session: %v", err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil || session.WeModRestarts == 0 {
+		t.Fatalf("invalid session file %q: %v", data, err)
+	}
+	if entries, _ := os.ReadDir(Dir()); len(entries) != 1 {
+		t.Fatalf("temp files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+)
+
+// Status prints the active launch sessions. With --json the session records
+// are printed as a JSON array.
+func Status(logger *logging.Logger, args []string) error {
+	logger = logger.WithComponent("registry.status")
+	asJSON := false
+	for _, arg := range args {
+		if arg != "--json" {
+			return errors.New("usage: wemod-launcher status [--json]")
+		}
+		asJSON = true
+	}
+
+	sessions, err := List()
+	if err != nil {
+		logger.Error("failed listing sessions: %v", err)
+		return err
+	}
+	logger.Debug("found %d active sessions in %s", len(sessions), Dir())
+
+	if asJSON {
+		if sessions == nil {
+			sessions = []Session{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sessions)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No active sessions.")
+		return nil
+	}
+	fmt.Printf("Active sessions: %d\n", len(sessions))
+	for _, session := range sessions {
+		since := session.StartedAt.Local().Format("2006-01-02 15:04:05")
+		if session.SessionID != "" {
+			since += ", session " + session.SessionID
+		}
+		fmt.Printf("  [%d] %s (%s), running since %s\n", session.PID, gameLabel(session), session.Source, since)
+		fmt.Printf("      prefix: %s\n", session.Prefix)
+		if session.Proton != "" {
+			fmt.Printf("      proton: %s\n", session.Proton)
+		} else if session.Wine != "" {
+			fmt.Printf("      wine:   %s\n", session.Wine)
+		}
+		if len(session.GameCommand) > 0 {
+			fmt.Printf("      game:   %s  %s\n", processLabel(session.GamePID), strings.Join(session.GameCommand, " "))
+		}
+		wemod := processLabel(session.WeModPID)
+		if session.WeModProfile != "" && Alive(session.WeModPID, "") {
+			wemod += ", profile " + session.WeModProfile
+		}
+		if session.WeModRestarts > 0 {
+			wemod += fmt.Sprintf(", restarted %d times by the watchdog", session.WeModRestarts)
+		}
+		fmt.Printf("      WeMod:  %s\n", wemod)
+		if session.WeModLog != "" {
+			fmt.Printf("      log:    %s\n", session.WeModLog)
+		}
+	}
+	return nil
+}
+
+func gameLabel(session Session) string {
+	switch {
+	case len(session.GameCommand) == 0:
+		return "standalone WeMod"
+	case session.GameID == "":
+		return "game"
+	default:
+		return "game " + session.GameID
+	}
+}
+
+func processLabel(pid int) string {
+	switch {
+	case pid == 0:
+		return "not started"
+	case Alive(pid, ""):
+		return "pid " + strconv.Itoa(pid)
+	default:
+		return "pid " + strconv.Itoa(pid) + " (exited)"
+	}
+}
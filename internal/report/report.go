@@ -0,0 +1,507 @@
+// Package report collects logs, config and environment details into a
+// tar.gz bundle for bug reports.
+package report
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/compat"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/doctor"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/launch"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/proton"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+// defaultSessions is the number of launcher runs included by default.
+const defaultSessions = 3
+
+// versionTimeout bounds "wine --version".
+const versionTimeout = 10 * time.Second
+
+var (
+	// textSessionPattern and jsonSessionPattern extract the session ID of a
+	// launcher log line in text and JSON format.
+	textSessionPattern = regexp.MustCompile(`^\S+ \S+ \[([0-9a-f]{8})\] `)
+	jsonSessionPattern = regexp.MustCompile(`"session_id":"([0-9a-f]{8})"`)
+	// prefixLinePattern matches the prefix logged by every launch.
+	prefixLinePattern = regexp.MustCompile(`using WeMod prefix: (.+?) \(source=`)
+	osNamePattern     = regexp.MustCompile(`(?m)^PRETTY_NAME="?([^"\n]*)`)
+	// tomlValuePattern matches key = value pairs of a TOML line, including
+	// inline tables.
+	tomlValuePattern = regexp.MustCompile(`([A-Za-z0-9_.-]+|"[^"]*")(\s*=\s*)("(?:[^"\\]|\\.)*"|'[^']*'|[^,}\s]+)`)
+)
+
+// Options select what goes into a report.
+type Options struct {
+	// Output is the path of the tar.gz file.
+	Output string
+	// Sessions is the number of most recent launcher runs whose logs are
+	// included.
+	Sessions int
+}
+
+// Run implements "report [--sessions N] [--output <file>]".
+func Run(ctx context.Context, cfg *config.Config, logger *logging.Logger, args []string) error {
+	logger = logger.WithComponent("report")
+	options, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	if options.Output == "" {
+		options.Output = fmt.Sprintf("wemod-report-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	logger.Info("creating report %s (last %d sessions)", options.Output, options.Sessions)
+
+	bundle, err := collect(ctx, cfg, logger, options)
+	if err != nil {
+		return err
+	}
+	if err := bundle.write(options.Output); err != nil {
+		logger.Error("failed writing report: %v", err)
+		return err
+	}
+	logger.Info("report written: %s (%d files)", options.Output, len(bundle.files))
+	fmt.Printf("Report written to %s\n", options.Output)
+	fmt.Println("Secrets are masked, but please check the files before sharing the report.")
+	return nil
+}
+
+func parseArgs(args []string) (Options, error) {
+	options := Options{Sessions: defaultSessions}
+	usage := errors.New("usage: wemod-launcher report [--sessions <n>] [--output <file.tar.gz>]")
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--sessions" && name != "--output" {
+			return options, usage
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return options, fmt.Errorf("missing value for %s", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--sessions":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return options, fmt.Errorf("invalid --sessions value %q: must be a positive number", value)
+			}
+			options.Sessions = n
+		case "--output":
+			options.Output = value
+		}
+	}
+	return options, nil
+}
+
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundle is the report content. Problems while collecting are listed in
+// report.txt instead of failing the whole report.
+type bundle struct {
+	redactor *logging.Redactor
+	files    []bundleFile
+	problems []string
+}
+
+func (b *bundle) add(name string, data []byte) {
+	b.files = append(b.files, bundleFile{name: name, data: data})
+}
+
+// addRedacted adds text with secrets masked line by line.
+func (b *bundle) addRedacted(name string, data []byte) {
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = b.redactor.String(line)
+	}
+	b.add(name, []byte(strings.Join(lines, "")))
+}
+
+func (b *bundle) problem(format string, args ...any) {
+	b.problems = append(b.problems, fmt.Sprintf(format, args...))
+}
+
+func collect(ctx context.Context, cfg *config.Config, logger *logging.Logger, options Options) (*bundle, error) {
+	redactor, err := logging.NewRedactor(cfg.Redact.Keys, cfg.Redact.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle{redactor: redactor}
+
+	sessions, lines := b.collectLauncherLog(cfg, logger.SessionID(), options.Sessions)
+	logger.Debug("selected sessions: %v", sessions)
+	b.collectWeModLogs(cfg, sessions)
+	b.collectConfig(cfg)
+	b.collectDoctor(ctx, cfg, logger)
+
+	running, err := registry.List()
+	if err != nil {
+		b.problem("list running sessions: %v", err)
+	}
+	prefixes := b.collectMarkers(cfg, lines, running)
+	protons := protonDirs(prefixes, running)
+	if data, err := os.ReadFile(compat.DefaultPath(cfg.Paths.WorkDir)); err == nil {
+		b.add("compat.json", data)
+	}
+
+	var summary bytes.Buffer
+	writeSummary(ctx, &summary, cfg, sessions, running, protons)
+	if len(b.problems) > 0 {
+		fmt.Fprintln(&summary, "\nProblems while collecting:")
+		for _, problem := range b.problems {
+			fmt.Fprintf(&summary, "  - %s\n", problem)
+		}
+	}
+	fmt.Fprintln(&summary, "\nFiles:")
+	for _, file := range b.files {
+		fmt.Fprintf(&summary, "  %s\n", file.name)
+	}
+	b.files = append([]bundleFile{{name: "report.txt", data: summary.Bytes()}}, b.files...)
+	return b, nil
+}
+
+// collectLauncherLog adds the log lines of the last n launcher runs, except
+// the run creating the report. It returns the selected session IDs, newest
+// first, and the selected lines.
+func (b *bundle) collectLauncherLog(cfg *config.Config, self string, n int) ([]string, []string) {
+	if cfg.General.LogFile == "" {
+		b.problem("general.log_file is empty; no launcher log to include")
+		return nil, nil
+	}
+	files, err := logging.LogFiles(cfg.General.LogFile)
+	if err != nil {
+		b.problem("list launcher logs: %v", err)
+		return nil, nil
+	}
+
+	var sessions []string
+	selected := map[string]bool{}
+	var chunks [][]string
+	// Read from the newest file back until n sessions were found and a file
+	// has no line of them left.
+	for i := len(files) - 1; i >= 0; i-- {
+		lines, err := readLines(files[i])
+		if err != nil {
+			b.problem("read %s: %v", files[i], err)
+			continue
+		}
+		var kept []string
+		for j := len(lines) - 1; j >= 0; j-- {
+			id := sessionID(lines[j])
+			if id == "" || id == self {
+				continue
+			}
+			if !selected[id] && len(sessions) < n {
+				selected[id] = true
+				sessions = append(sessions, id)
+			}
+			if selected[id] {
+				kept = append(kept, lines[j])
+			}
+		}
+		if len(kept) == 0 && len(sessions) == n {
+			break
+		}
+		chunks = append(chunks, kept)
+	}
+
+	var ordered []string
+	for i := len(chunks) - 1; i >= 0; i-- {
+		for j := len(chunks[i]) - 1; j >= 0; j-- {
+			ordered = append(ordered, chunks[i][j])
+		}
+	}
+	if len(ordered) == 0 {
+		b.problem("no launcher log lines with a session ID found")
+		return sessions, nil
+	}
+	b.addRedacted("launcher.log", []byte(strings.Join(ordered, "\n")+"\n"))
+	return sessions, ordered
+}
+
+// sessionID returns the session ID of a launcher log line.
+func sessionID(line string) string {
+	for _, pattern := range []*regexp.Regexp{textSessionPattern, jsonSessionPattern} {
+		if match := pattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// readLines reads a log file, decompressing ".gz" files.
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		reader = zr
+	}
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// collectWeModLogs adds the WeMod output logs of the selected sessions.
+func (b *bundle) collectWeModLogs(cfg *config.Config, sessions []string) {
+	dir := cfg.WeModLogDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			b.problem("read WeMod log dir: %v", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		for _, id := range sessions {
+			if !strings.HasSuffix(entry.Name(), "-"+id+".log") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				b.problem("read WeMod log %s: %v", entry.Name(), err)
+				continue
+			}
+			b.addRedacted("wemod/"+entry.Name(), data)
+		}
+	}
+}
+
+// collectConfig adds the config file with secret values masked.
+func (b *bundle) collectConfig(cfg *config.Config) {
+	data, err := os.ReadFile(cfg.Meta.ConfigPath)
+	if err != nil {
+		b.problem("read config: %v", err)
+		return
+	}
+	b.add("config.toml", []byte(redactTOML(string(data), b.redactor)))
+}
+
+// redactTOML masks values of secret keys (including keys of inline tables
+// such as env = { API_KEY = "..." }) and secrets inside values, e.g. tokens
+// in hook commands.
+func redactTOML(data string, redactor *logging.Redactor) string {
+	lines := strings.SplitAfter(data, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		line = tomlValuePattern.ReplaceAllStringFunc(line, func(pair string) string {
+			match := tomlValuePattern.FindStringSubmatch(pair)
+			if !redactor.IsSecretKey(strings.Trim(match[1], `"`)) {
+				return pair
+			}
+			return match[1] + match[2] + `"***"`
+		})
+		lines[i] = redactor.String(line)
+	}
+	return strings.Join(lines, "")
+}
+
+// collectDoctor adds the output of the doctor command.
+func (b *bundle) collectDoctor(ctx context.Context, cfg *config.Config, logger *logging.Logger) {
+	var output bytes.Buffer
+	if err := doctor.Run(ctx, cfg, logger.WithOutput(&output), doctor.Options{}); err != nil {
+		fmt.Fprintf(&output, "doctor failed: %v\n", err)
+	}
+	b.addRedacted("doctor.txt", output.Bytes())
+}
+
+// collectMarkers adds the runtime markers of the own prefix and of the game
+// prefixes used by the selected and running sessions. It returns the
+// prefixes with a marker.
+func (b *bundle) collectMarkers(cfg *config.Config, lines []string, running []registry.Session) []string {
+	seen := map[string]bool{}
+	var prefixes []string
+	add := func(prefix string) {
+		prefix = filepath.Clean(strings.TrimSpace(prefix))
+		if prefix == "." || seen[prefix] {
+			return
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+	add(cfg.Paths.PrefixDir)
+	for _, line := range lines {
+		if match := prefixLinePattern.FindStringSubmatch(line); match != nil {
+			add(match[1])
+		}
+	}
+	for _, session := range running {
+		add(session.Prefix)
+	}
+
+	var withMarker []string
+	var index bytes.Buffer
+	for i, prefix := range prefixes {
+		data, err := os.ReadFile(launch.RuntimeMarkerPath(prefix))
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(&index, "%s: no marker\n", prefix)
+			continue
+		}
+		if err != nil {
+			b.problem("read marker of %s: %v", prefix, err)
+			continue
+		}
+		label := filepath.Base(prefix)
+		if label == "pfx" {
+			// Steam prefixes are compatdata/<appid>/pfx.
+			label = filepath.Base(filepath.Dir(prefix))
+		}
+		name := fmt.Sprintf("markers/%d-%s.json", i+1, label)
+		fmt.Fprintf(&index, "%s: %s\n", prefix, name)
+		b.add(name, data)
+		withMarker = append(withMarker, prefix)
+	}
+	if index.Len() > 0 {
+		b.add("markers/index.txt", index.Bytes())
+	}
+	return withMarker
+}
+
+// protonDirs returns the Proton builds recorded in the markers of prefixes
+// and used by running sessions.
+func protonDirs(prefixes []string, running []registry.Session) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		dir := proton.Inspect(path).Dir
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, prefix := range prefixes {
+		data, err := os.ReadFile(launch.RuntimeMarkerPath(prefix))
+		if err != nil {
+			continue
+		}
+		var marker struct {
+			ProtonPath string `json:"proton_path"`
+		}
+		if json.Unmarshal(data, &marker) == nil {
+			add(marker.ProtonPath)
+		}
+	}
+	for _, session := range running {
+		add(session.ProtonPath)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func writeSummary(ctx context.Context, w io.Writer, cfg *config.Config, sessions []string, running []registry.Session, protons []string) {
+	fmt.Fprintf(w, "wemod-launcher %s report, created %s\n\n", config.AppVersion, time.Now().Format(time.RFC3339))
+	if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		fmt.Fprintf(w, "Kernel:         %s\n", strings.TrimSpace(string(release)))
+	}
+	if osRelease, err := os.ReadFile("/etc/os-release"); err == nil {
+		if match := osNamePattern.FindSubmatch(osRelease); match != nil {
+			fmt.Fprintf(w, "OS:             %s\n", match[1])
+		}
+	}
+	product, version := compat.DetectWeModVersion(cfg.Paths.WeModExePath)
+	if version == "" {
+		product, version = "WeMod", "unknown"
+	}
+	fmt.Fprintf(w, "%-15s %s (%s)\n", product+":", version, cfg.Paths.WeModExePath)
+	fmt.Fprintf(w, "System wine:    %s\n", commandVersion(ctx, "wine", "--version"))
+	fmt.Fprintf(w, "Own prefix:     %s\n", cfg.Paths.PrefixDir)
+	fmt.Fprintf(w, "Log file:       %s (format %s)\n", cfg.General.LogFile, cfg.General.LogFormat)
+
+	fmt.Fprintln(w, "\nProton builds:")
+	if len(protons) == 0 {
+		fmt.Fprintln(w, "  none recorded")
+	}
+	for _, dir := range protons {
+		info := proton.Inspect(dir)
+		fmt.Fprintf(w, "  %s: %s [%s]\n", dir, info.String(), info.VersionFile)
+	}
+
+	fmt.Fprintf(w, "\nIncluded sessions (newest first): %s\n", strings.Join(sessions, ", "))
+	fmt.Fprintf(w, "Running launches: %d\n", len(running))
+	for _, session := range running {
+		fmt.Fprintf(w, "  pid %d session %s: %s prefix %s, WeMod pid %d\n", session.PID, session.SessionID, session.Source, session.Prefix, session.WeModPID)
+	}
+}
+
+// commandVersion returns the first output line of a version command.
+func commandVersion(ctx context.Context, name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return fmt.Sprintf("unavailable (%v)", err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return line
+}
+
+// write stores the bundle as a tar.gz with all files below one directory.
+func (b *bundle) write(path string) error {
+	base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	defer file.Close()
+	zw := gzip.NewWriter(file)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+	for _, f := range b.files {
+		header := &tar.Header{Name: base + "/" + f.name, Mode: 0o644, Size: int64(len(f.data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package report
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/config"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/launch"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/logging"
+	"github.com/NichSchlagen/wemod-proton-launcher-go/internal/registry"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer file.Close()
+	zw := gzip.NewWriter(file)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close %s: %v", path, err)
+	}
+}
+
+func readReport(t *testing.T, path string) map[string]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open report: %v", err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read %s: %v", header.Name, err)
+		}
+		name, ok := strings.CutPrefix(header.Name, "bundle/")
+		if !ok {
+			t.Fatalf("entry outside report dir: %s", header.Name)
+		}
+		files[name] = string(data)
+	}
+}
+
+func TestRun_CollectsLastSessions(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir := t.TempDir()
+	gamePrefix := filepath.Join(dir, "compatdata", "1245620", "pfx")
+	writeFile(t, launch.RuntimeMarkerPath(gamePrefix), `{"schema_version":1,"proton_path":"/nonexistent/Proton 9.0"}`)
+
+	cfg := &config.Config{}
+	cfg.General.LogLevel = "error"
+	cfg.General.LogFile = filepath.Join(dir, "logs", "wemod-launcher.log")
+	cfg.Paths.WorkDir = dir
+	cfg.Paths.PrefixDir = filepath.Join(dir, "wemod_prefix")
+	cfg.Paths.DownloadDir = filepath.Join(dir, "downloads")
+	cfg.Paths.WeModExePath = filepath.Join(dir, "wemod_bin", "WeMod.exe")
+	cfg.Meta.ConfigPath = filepath.Join(dir, "wemod.toml")
+	cfg.Redact.Keys = []string{"steamgrid"}
+	writeFile(t, cfg.Meta.ConfigPath, strings.Join([]string{
+		"[general]",
+		"log_level = \"info\"",
+		"# STEAM_API_KEY = \"comment stays\"",
+		"[wemod]",
+		"env = { STEAM_API_KEY = \"abc123\", WINEDEBUG = \"-all\" }",
+		"[hooks]",
+		"post_game_exit = [\"upload --token s3cr3t\"]",
+		"steamgrid_id = 'xyz'",
+	}, "\n")+"\n")
+
+	// Session 1111 starts in the rotated log and continues in the current
+	// one; 0000 is older than the selected sessions.
+	rotated := filepath.Join(dir, "logs", "wemod-launcher-20261017-100000.000.log.gz")
+	writeGzip(t, rotated, strings.Join([]string{
+		"2026/10/17 09:00:00.000000 [00000000] [INFO] [app] old run",
+		"2026/10/17 10:00:00.000000 [11111111] [INFO] [launch] using WeMod prefix: " + gamePrefix + " (source=steam)",
+		"pre-session line without id",
+	}, "\n")+"\n")
+	writeFile(t, cfg.General.LogFile, strings.Join([]string{
+		"2026/10/17 10:00:01.000000 [11111111] [INFO] [launch] GITHUB_TOKEN=ghp_x",
+		`{"timestamp":"2026-10-17T11:00:00Z","level":"info","message":"json run","session_id":"22222222"}`,
+		"2026/10/17 12:00:00.000000 [33333333] [INFO] [cli] command started: report",
+	}, "\n")+"\n")
+	writeFile(t, filepath.Join(cfg.WeModLogDir(), "wemod-20261017-100000-11111111.log"), "err:module:x --password hunter2\n")
+	writeFile(t, filepath.Join(cfg.WeModLogDir(), "wemod-20261017-090000-00000000.log"), "old\n")
+
+	logger, err := logging.New(&config.Config{General: config.GeneralConfig{LogLevel: "info"}})
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	// Pretend the report runs as session 33333333.
+	self := logger.SessionID()
+	writeFile(t, cfg.General.LogFile, strings.ReplaceAll(mustRead(t, cfg.General.LogFile), "33333333", self))
+
+	output := filepath.Join(dir, "bundle.tar.gz")
+	if err := Run(context.Background(), cfg, logger, []string{"--sessions", "2", "--output=" + output}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	files := readReport(t, output)
+
+	wantLog := strings.Join([]string{
+		"2026/10/17 10:00:00.000000 [11111111] [INFO] [launch] using WeMod prefix: " + gamePrefix + " (source=steam)",
+		"2026/10/17 10:00:01.000000 [11111111] [INFO] [launch] GITHUB_TOKEN=***",
+		`{"timestamp":"2026-10-17T11:00:00Z","level":"info","message":"json run","session_id":"22222222"}`,
+	}, "\n") + "\n"
+	if files["launcher.log"] != wantLog {
+		t.Fatalf("unexpected launcher.log:\n%s", files["launcher.log"])
+	}
+	if got := files["wemod/wemod-20261017-100000-11111111.log"]; got != "err:module:x --password ***\n" {
+		t.Fatalf("unexpected WeMod log: %q", got)
+	}
+	if _, ok := files["wemod/wemod-20261017-090000-00000000.log"]; ok {
+		t.Fatal("WeMod log of an unselected session included")
+	}
+	config := files["config.toml"]
+	for _, secret := range []string{"abc123", "s3cr3t", "xyz"} {
+		if strings.Contains(config, secret) {
+			t.Fatalf("config leaks %q:\n%s", secret, config)
+		}
+	}
+	if !strings.Contains(config, `WINEDEBUG = "-all"`) || !strings.Contains(config, `# STEAM_API_KEY = "comment stays"`) {
+		t.Fatalf("config redacted too much:\n%s", config)
+	}
+	if !strings.Contains(files["markers/2-1245620.json"], "Proton 9.0") {
+		t.Fatalf("marker missing: %v", files["markers/index.txt"])
+	}
+	for _, name := range []string{"report.txt", "doctor.txt"} {
+		if files[name] == "" {
+			t.Fatalf("%s missing or empty", name)
+		}
+	}
+	if !strings.Contains(files["report.txt"], "Included sessions (newest first): 22222222, 11111111") {
+		t.Fatalf("unexpected report.txt:\n%s", files["report.txt"])
+	}
+}
+
+func mustRead(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestParseArgs(t *testing.T) {
+	options, err := parseArgs(nil)
+	if err != nil || options.Sessions != defaultSessions || options.Output != "" {
+		t.Fatalf("unexpected defaults: %+v, %v", options, err)
+	}
+	for _, args := range [][]string{{"--sessions", "0"}, {"--sessions"}, {"--verbose"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %q", args)
+		}
+	}
+}
+
+func TestProtonDirs_RunningSessionBehindRuntimeWrapper(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "GE-Proton9-20")
+	writeFile(t, filepath.Join(dir, "proton"), "#!/bin/sh\n")
+	session := registry.Session{
+		ProtonPath:  filepath.Join(dir, "proton"),
+		GameCommand: []string{"/steam/SteamLinuxRuntime_sniper/_v2-entry-point", "--verb=waitforexitandrun", "--", filepath.Join(dir, "proton"), "waitforexitandrun", "game.exe"},
+	}
+	if got := protonDirs(nil, []registry.Session{session}); len(got) != 1 || got[0] != dir {
+		t.Fatalf("unexpected Proton dirs: %v", got)
+	}
+}
@@ -62,7 +62,9 @@ func Start(ctx context.Context, logger *logging.Logger, name string, args []stri
 	return cmd, nil
 }
 
-func StartDetached(ctx context.Context, logger *logging.Logger, name string, args []string, env map[string]string) (*exec.Cmd, error) {
+// StartDetached starts a process in its own process group. Its stdout and
+// stderr go to output, or are discarded when output is nil.
+func StartDetached(ctx context.Context, logger *logging.Logger, name string, args []string, env map[string]string, output *os.File) (*exec.Cmd, error) {
 	logger = logger.WithComponent("runtime")
 	logger.Debug("start detached command: %s %s", name, strings.Join(args, " "))
 	// Detached GUI process must not be tied to launcher context; otherwise it gets
@@ -79,6 +81,12 @@ func StartDetached(ctx context.Context, logger *logging.Logger, name string, arg
 	cmd.Stdin = devNull
 	cmd.Stdout = devNull
 	cmd.Stderr = devNull
+	// An *os.File is handed to the child directly, so output keeps flowing
+	// after the launcher exits.
+	if output != nil {
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
 
 	if len(env) > 0 {
 		cmd.Env = os.Environ()
@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Roles of processes in a Wine prefix.
+const (
+	RoleGame   = "game"
+	RoleWeMod  = "wemod"
+	RoleHelper = "helper"
+)
+
+// helperNames are Wine/Proton service processes that belong to no game.
+var helperNames = map[string]bool{
+	"wineserver":       true,
+	"wineserver64":     true,
+	"wine-preloader":   true,
+	"wine64-preloader": true,
+	"services.exe":     true,
+	"winedevice.exe":   true,
+	"plugplay.exe":     true,
+	"svchost.exe":      true,
+	"explorer.exe":     true,
+	"rpcss.exe":        true,
+	"tabtip.exe":       true,
+	"wineboot.exe":     true,
+	"winedbg.exe":      true,
+	"conhost.exe":      true,
+	"start.exe":        true,
+	"rundll32.exe":     true,
+	"steam.exe":        true,
+	"mscorsvw.exe":     true,
+	"ngen.exe":         true,
+}
+
+// WineProcess is a process running in a Wine prefix.
+type WineProcess struct {
+	PID  int
+	PPID int
+	Args []string
+	Role string
+}
+
+// Name returns the base name of the executable, for both Unix and Windows
+// paths ("C:\windows\system32\services.exe" -> "services.exe").
+func (p WineProcess) Name() string {
+	if len(p.Args) == 0 {
+		return ""
+	}
+	name := p.Args[0]
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(name)
+}
+
+// PrefixProcesses are the processes of one Wine prefix grouped by role.
+type PrefixProcesses struct {
+	Game    []WineProcess
+	WeMod   []WineProcess
+	Helpers []WineProcess
+}
+
+// All returns every process of the prefix ordered by PID.
+func (p PrefixProcesses) All() []WineProcess {
+	all := make([]WineProcess, 0, len(p.Game)+len(p.WeMod)+len(p.Helpers))
+	all = append(append(append(all, p.Game...), p.WeMod...), p.Helpers...)
+	sort.Slice(all, func(a, b int) bool { return all[a].PID < all[b].PID })
+	return all
+}
+
+// Empty reports whether no process runs in the prefix.
+func (p PrefixProcesses) Empty() bool {
+	return len(p.Game)+len(p.WeMod)+len(p.Helpers) == 0
+}
+
+// ScanPrefix finds the processes running in a Wine prefix: processes whose
+// environment has WINEPREFIX set to prefix, and processes started below the
+// wineserver of that prefix.
+func ScanPrefix(prefix string) PrefixProcesses {
+	return scanPrefix("/proc", prefix)
+}
+
+type procEntry struct {
+	ppid   int
+	args   []string
+	prefix string
+}
+
+func scanPrefix(procRoot, prefix string) PrefixProcesses {
+	var result PrefixProcesses
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return result
+	}
+	prefix = filepath.Clean(prefix)
+	self := os.Getpid()
+
+	procs := map[int]procEntry{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join(procRoot, entry.Name())
+		cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		procs[pid] = procEntry{
+			ppid:   readPPID(dir),
+			args:   strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"),
+			prefix: readEnvValue(filepath.Join(dir, "environ"), "WINEPREFIX"),
+		}
+	}
+
+	members := map[int]bool{}
+	wineservers := map[int]bool{}
+	for pid, proc := range procs {
+		if proc.prefix == "" || filepath.Clean(proc.prefix) != prefix {
+			continue
+		}
+		members[pid] = true
+		if name := (WineProcess{Args: proc.args}).Name(); name == "wineserver" || name == "wineserver64" {
+			wineservers[pid] = true
+		}
+	}
+	if len(wineservers) > 0 {
+		for pid := range procs {
+			if !members[pid] && hasAncestor(procs, pid, wineservers) {
+				members[pid] = true
+			}
+		}
+	}
+
+	pids := make([]int, 0, len(members))
+	for pid := range members {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		process := WineProcess{PID: pid, PPID: procs[pid].ppid, Args: procs[pid].args}
+		switch process.Role = classify(process); process.Role {
+		case RoleWeMod:
+			result.WeMod = append(result.WeMod, process)
+		case RoleHelper:
+			result.Helpers = append(result.Helpers, process)
+		default:
+			result.Game = append(result.Game, process)
+		}
+	}
+	return result
+}
+
+func classify(process WineProcess) string {
+	for _, arg := range process.Args {
+		lower := strings.ToLower(arg)
+		if strings.Contains(lower, "wemod.exe") || strings.Contains(lower, "wand.exe") {
+			return RoleWeMod
+		}
+	}
+	if helperNames[process.Name()] {
+		return RoleHelper
+	}
+	return RoleGame
+}
+
+func hasAncestor(procs map[int]procEntry, pid int, ancestors map[int]bool) bool {
+	// The depth limit guards against loops from PID reuse during the scan.
+	for depth := 0; depth < 64; depth++ {
+		proc, ok := procs[pid]
+		if !ok || proc.ppid <= 1 {
+			return false
+		}
+		if ancestors[proc.ppid] {
+			return true
+		}
+		pid = proc.ppid
+	}
+	return false
+}
+
+// readPPID reads the parent PID from /proc/<pid>/stat. The command name in
+// parentheses may contain spaces, so fields are counted after the last ')'.
+func readPPID(dir string) int {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0
+	}
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+func readEnvValue(path, key string) string {
+	environ, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	prefix := []byte(key + "=")
+	for _, entry := range bytes.Split(environ, []byte{0}) {
+		if bytes.HasPrefix(entry, prefix) {
+			return string(entry[len(prefix):])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type fakeProc struct {
+	pid    int
+	ppid   int
+	comm   string
+	args   []string
+	prefix string
+}
+
+func writeFakeProc(t *testing.T, root string, proc fakeProc) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(proc.pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("create proc dir: %v", err)
+	}
+	stat := fmt.Sprintf("%d (%s) S %d %d %d 0 -1\n", proc.pid, proc.comm, proc.ppid, proc.pid, proc.pid)
+	environ := "HOME=/home/user\x00"
+	if proc.prefix != "" {
+		environ += "WINEPREFIX=" + proc.prefix + "\x00"
+	}
+	files := map[string]string{
+		"stat":    stat,
+		"cmdline": strings.Join(proc.args, "\x00") + "\x00",
+		"environ": environ,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func pids(procs []WineProcess) []int {
+	var result []int
+	for _, proc := range procs {
+		result = append(result, proc.PID)
+	}
+	return result
+}
+
+func TestScanPrefix_GroupsProcessesByRole(t *testing.T) {
+	root := t.TempDir()
+	const prefix = "/games/pfx"
+	for _, proc := range []fakeProc{
+		{pid: 100, ppid: 1, comm: "wineserver", args: []string{"/opt/proton/files/bin/wineserver"}, prefix: prefix + "/"},
+		{pid: 101, ppid: 1, comm: "services.exe", args: []string{`C:\windows\system32\services.exe`}, prefix: prefix},
+		{pid: 102, ppid: 50, comm: "WeMod.exe", args: []string{`C:\users\steamuser\AppData\Local\WeMod\WeMod.exe`}, prefix: prefix},
+		// Renderer without WINEPREFIX, started below the wineserver.
+		{pid: 103, ppid: 100, comm: "WeMod.exe", args: []string{`C:\users\steamuser\AppData\Local\WeMod\app-9\WeMod.exe`, "--type=renderer"}},
+		{pid: 104, ppid: 103, comm: "Game Main (x64)", args: []string{`Z:\games\Game\Game.exe`, "-dx12"}},
+		// Same processes in another prefix are ignored.
+		{pid: 200, ppid: 1, comm: "wineserver", args: []string{"wineserver"}, prefix: "/games/other"},
+		{pid: 201, ppid: 200, comm: "Other.exe", args: []string{`Z:\games\Other\Other.exe`}},
+		{pid: 202, ppid: 1, comm: "bash", args: []string{"bash"}},
+	} {
+		writeFakeProc(t, root, proc)
+	}
+	// Kernel threads have an empty cmdline and are skipped.
+	writeFakeProc(t, root, fakeProc{pid: 2, ppid: 0, comm: "kthreadd"})
+	if err := os.WriteFile(filepath.Join(root, "2", "cmdline"), nil, 0o644); err != nil {
+		t.Fatalf("write cmdline: %v", err)
+	}
+
+	result := scanPrefix(root, prefix)
+	if got := pids(result.WeMod); fmt.Sprint(got) != "[102 103]" {
+		t.Fatalf("unexpected WeMod processes: %v", got)
+	}
+	if got := pids(result.Helpers); fmt.Sprint(got) != "[100 101]" {
+		t.Fatalf("unexpected helper processes: %v", got)
+	}
+	if got := pids(result.Game); fmt.Sprint(got) != "[104]" {
+		t.Fatalf("unexpected game processes: %v", got)
+	}
+	if got := pids(result.All()); fmt.Sprint(got) != "[100 101 102 103 104]" {
+		t.Fatalf("unexpected process order: %v", got)
+	}
+	if result.Game[0].PPID != 103 || result.Game[0].Role != RoleGame || result.Game[0].Name() != "game.exe" {
+		t.Fatalf("unexpected game process: %+v", result.Game[0])
+	}
+
+	if empty := scanPrefix(root, "/games/missing"); !empty.Empty() {
+		t.Fatalf("expected no processes, got %+v", empty)
+	}
+}
@@ -0,0 +1,383 @@
+package winereg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Type is a Windows registry value type (REG_*).
+type Type uint32
+
+const (
+	TypeNone   Type = 0
+	TypeString Type = 1
+	TypeExpand Type = 2
+	TypeBinary Type = 3
+	TypeDWord  Type = 4
+	TypeMulti  Type = 7
+	TypeQWord  Type = 11
+)
+
+// maxLineLength is the column after which Wine wraps hex data.
+const maxLineLength = 76
+
+// Value is a registry value. Data holds the raw Windows representation:
+// UTF-16LE with terminating NUL for string types, little-endian integers for
+// DWORD/QWORD and plain bytes otherwise. The default value has an empty Name.
+type Value struct {
+	Name string
+	Type Type
+	Data []byte
+}
+
+// StringValue returns a REG_SZ value.
+func StringValue(name, s string) Value {
+	return Value{Name: name, Type: TypeString, Data: encodeUTF16(s + "\x00")}
+}
+
+// ExpandStringValue returns a REG_EXPAND_SZ value.
+func ExpandStringValue(name, s string) Value {
+	return Value{Name: name, Type: TypeExpand, Data: encodeUTF16(s + "\x00")}
+}
+
+// MultiStringValue returns a REG_MULTI_SZ value.
+func MultiStringValue(name string, items []string) Value {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(item)
+		b.WriteByte(0)
+	}
+	b.WriteByte(0)
+	return Value{Name: name, Type: TypeMulti, Data: encodeUTF16(b.String())}
+}
+
+// DWordValue returns a REG_DWORD value.
+func DWordValue(name string, n uint32) Value {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, n)
+	return Value{Name: name, Type: TypeDWord, Data: data}
+}
+
+// BinaryValue returns a REG_BINARY value.
+func BinaryValue(name string, data []byte) Value {
+	return Value{Name: name, Type: TypeBinary, Data: append([]byte(nil), data...)}
+}
+
+// Text returns the content of a REG_SZ or REG_EXPAND_SZ value without the
+// terminating NUL.
+func (v Value) Text() (string, bool) {
+	if v.Type != TypeString && v.Type != TypeExpand {
+		return "", false
+	}
+	s, ok := decodeUTF16(v.Data)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSuffix(s, "\x00"), true
+}
+
+// Strings returns the items of a REG_MULTI_SZ value.
+func (v Value) Strings() ([]string, bool) {
+	if v.Type != TypeMulti {
+		return nil, false
+	}
+	s, ok := decodeUTF16(v.Data)
+	if !ok {
+		return nil, false
+	}
+	s = strings.TrimRight(s, "\x00")
+	if s == "" {
+		return []string{}, true
+	}
+	return strings.Split(s, "\x00"), true
+}
+
+// Uint32 returns the number stored in a REG_DWORD value.
+func (v Value) Uint32() (uint32, bool) {
+	if v.Type != TypeDWord || len(v.Data) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(v.Data), true
+}
+
+func (v Value) clone() Value {
+	v.Data = append([]byte(nil), v.Data...)
+	return v
+}
+
+// parseValue parses a complete value line (continuations already joined).
+func parseValue(line string) (Value, error) {
+	var value Value
+	var rest string
+	if strings.HasPrefix(line, "@=") {
+		rest = line[2:]
+	} else {
+		name, after, err := unescapeString(line[1:], '"')
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid value name: %w", err)
+		}
+		if !strings.HasPrefix(after, "=") {
+			return Value{}, fmt.Errorf("missing '=' after value name %q", name)
+		}
+		value.Name = name
+		rest = after[1:]
+	}
+
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		value.Type = TypeString
+		return parseStringData(value, rest)
+	case strings.HasPrefix(rest, "str("):
+		typ, after, err := parseTypeSuffix(rest[len("str"):])
+		if err != nil {
+			return Value{}, err
+		}
+		value.Type = typ
+		return parseStringData(value, after)
+	case strings.HasPrefix(rest, "dword:"):
+		n, err := strconv.ParseUint(strings.TrimSpace(rest[len("dword:"):]), 16, 32)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid dword data for %q: %w", value.Name, err)
+		}
+		return DWordValue(value.Name, uint32(n)), nil
+	case strings.HasPrefix(rest, "hex:"):
+		value.Type = TypeBinary
+		return parseHexData(value, rest[len("hex:"):])
+	case strings.HasPrefix(rest, "hex("):
+		typ, after, err := parseTypeSuffix(rest[len("hex"):])
+		if err != nil {
+			return Value{}, err
+		}
+		value.Type = typ
+		return parseHexData(value, after)
+	default:
+		return Value{}, fmt.Errorf("unsupported data for value %q", value.Name)
+	}
+}
+
+// parseTypeSuffix parses "(7):" and returns the type and the remainder.
+func parseTypeSuffix(s string) (Type, string, error) {
+	end := strings.Index(s, "):")
+	if !strings.HasPrefix(s, "(") || end < 0 {
+		return 0, "", fmt.Errorf("invalid value type in %q", s)
+	}
+	n, err := strconv.ParseUint(s[1:end], 16, 32)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid value type %q: %w", s[1:end], err)
+	}
+	return Type(n), s[end+2:], nil
+}
+
+func parseStringData(value Value, s string) (Value, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return Value{}, fmt.Errorf("expected quoted data for value %q", value.Name)
+	}
+	text, rest, err := unescapeString(s[1:], '"')
+	if err != nil {
+		return Value{}, fmt.Errorf("invalid string data for %q: %w", value.Name, err)
+	}
+	if strings.TrimSpace(rest) != "" {
+		return Value{}, fmt.Errorf("trailing data after string value %q", value.Name)
+	}
+	value.Data = encodeUTF16(text + "\x00")
+	return value, nil
+}
+
+func parseHexData(value Value, s string) (Value, error) {
+	value.Data = []byte{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		b, err := strconv.ParseUint(field, 16, 8)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid hex byte %q in value %q", field, value.Name)
+		}
+		value.Data = append(value.Data, byte(b))
+	}
+	return value, nil
+}
+
+// encodeValue renders a value the way Wine's registry writer does.
+func encodeValue(v Value) []string {
+	prefix := "@="
+	if v.Name != "" {
+		prefix = `"` + escapeString(v.Name, '"') + `"=`
+	}
+
+	switch v.Type {
+	case TypeString, TypeExpand, TypeMulti:
+		if s, ok := decodeUTF16(v.Data); ok && strings.HasSuffix(s, "\x00") {
+			typePrefix := ""
+			if v.Type != TypeString {
+				typePrefix = fmt.Sprintf("str(%x):", uint32(v.Type))
+			}
+			return []string{prefix + typePrefix + `"` + escapeString(strings.TrimSuffix(s, "\x00"), '"') + `"`}
+		}
+	case TypeDWord:
+		if n, ok := v.Uint32(); ok {
+			return []string{fmt.Sprintf("%sdword:%08x", prefix, n)}
+		}
+	}
+
+	line := prefix + "hex:"
+	if v.Type != TypeBinary {
+		line = fmt.Sprintf("%shex(%x):", prefix, uint32(v.Type))
+	}
+	var lines []string
+	count := len(line)
+	for i, b := range v.Data {
+		line += fmt.Sprintf("%02x", b)
+		count += 2
+		if i < len(v.Data)-1 {
+			line += ","
+			count++
+			if count > maxLineLength {
+				lines = append(lines, line+`\`)
+				line = "  "
+				count = 2
+			}
+		}
+	}
+	return append(lines, line)
+}
+
+// unescapeString decodes a Wine-escaped string up to the unescaped delimiter
+// and returns the decoded text and the remainder after the delimiter.
+func unescapeString(s string, delim byte) (string, string, error) {
+	units := make([]uint16, 0, len(s))
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c == delim {
+			return string(utf16.Decode(units)), s[i+1:], nil
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			units = utf16.AppendRune(units, r)
+			i += size
+			continue
+		}
+		i++
+		if i >= len(s) {
+			break
+		}
+		c = s[i]
+		switch c {
+		case 'a':
+			units = append(units, 7)
+			i++
+		case 'b':
+			units = append(units, 8)
+			i++
+		case 'e':
+			units = append(units, 27)
+			i++
+		case 'f':
+			units = append(units, 12)
+			i++
+		case 'n':
+			units = append(units, 10)
+			i++
+		case 'r':
+			units = append(units, 13)
+			i++
+		case 't':
+			units = append(units, 9)
+			i++
+		case 'v':
+			units = append(units, 11)
+			i++
+		case 'x':
+			i++
+			start := i
+			for i < len(s) && i-start < 4 && isHexDigit(s[i]) {
+				i++
+			}
+			if i == start {
+				units = append(units, 'x')
+				continue
+			}
+			n, _ := strconv.ParseUint(s[start:i], 16, 16)
+			units = append(units, uint16(n))
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			start := i
+			for i < len(s) && i-start < 3 && s[i] >= '0' && s[i] <= '7' {
+				i++
+			}
+			n, _ := strconv.ParseUint(s[start:i], 8, 16)
+			units = append(units, uint16(n))
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			units = utf16.AppendRune(units, r)
+			i += size
+		}
+	}
+	return "", "", fmt.Errorf("missing closing %q", delim)
+}
+
+// escapeString encodes s the way Wine's dump_strW does: control characters
+// use C escapes or octal, non-ASCII characters use \x escapes, and the
+// backslash and delimiter are escaped with a backslash.
+func escapeString(s string, delim byte) string {
+	const escapes = ".......abtnvfr.............e...."
+	units := utf16.Encode([]rune(s))
+	var b strings.Builder
+	for i, u := range units {
+		next := uint16(0)
+		if i+1 < len(units) {
+			next = units[i+1]
+		}
+		switch {
+		case u > 127:
+			if next < 128 && isHexDigit(byte(next)) {
+				fmt.Fprintf(&b, `\x%04x`, u)
+			} else {
+				fmt.Fprintf(&b, `\x%x`, u)
+			}
+		case u < 32:
+			if escapes[u] != '.' {
+				b.WriteByte('\\')
+				b.WriteByte(escapes[u])
+			} else if next >= '0' && next <= '7' {
+				fmt.Fprintf(&b, `\%03o`, u)
+			} else {
+				fmt.Fprintf(&b, `\%o`, u)
+			}
+		case u == '\\' || u == uint16(delim):
+			b.WriteByte('\\')
+			b.WriteByte(byte(u))
+		default:
+			b.WriteByte(byte(u))
+		}
+	}
+	return b.String()
+}
+
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[i*2:], u)
+	}
+	return data
+}
+
+func decodeUTF16(data []byte) (string, bool) {
+	if len(data)%2 != 0 {
+		return "", false
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units)), true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,413 @@
+// Package winereg reads and edits Wine registry files (system.reg, user.reg,
+// userdef.reg).
+//
+// Parsing is lossless: keys and values that are not modified are written
+// back byte for byte, including metadata lines such as #time= and #class=.
+// Only keys and values touched through the API are re-encoded, using the same
+// layout Wine itself writes.
+package winereg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the first line of every registry file Wine writes.
+const Header = "WINE REGISTRY Version 2"
+
+// File is a parsed Wine registry file.
+type File struct {
+	head           []string
+	keys           []*Key
+	noFinalNewline bool
+}
+
+// Key is a registry key section ([Name] timestamp) with its values.
+type Key struct {
+	name     string
+	modified int64
+	// header is the original section line. It is cleared when the name or
+	// timestamp changes so the line is re-encoded on write.
+	header  string
+	entries []entry
+}
+
+// entry is either a value or a raw line (metadata, comment or blank line).
+type entry struct {
+	raw   []string
+	value *Value
+}
+
+// Load reads and parses the registry file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses the content of a Wine registry file.
+func Parse(data []byte) (*File, error) {
+	content := string(data)
+	f := &File{}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		f.noFinalNewline = true
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r") != Header {
+		return nil, errors.New("not a Wine registry file (missing version header)")
+	}
+
+	var current *Key
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimRight(line, "\r")
+		lineNo := i + 1
+
+		if strings.HasPrefix(trimmed, "[") {
+			name, modified, err := parseKeyHeader(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			current = &Key{name: name, modified: modified, header: line}
+			f.keys = append(f.keys, current)
+			continue
+		}
+		if current == nil {
+			f.head = append(f.head, line)
+			continue
+		}
+		if !strings.HasPrefix(trimmed, `"`) && !strings.HasPrefix(trimmed, "@") {
+			current.entries = append(current.entries, entry{raw: []string{line}})
+			continue
+		}
+
+		raw := []string{line}
+		text := trimmed
+		for strings.HasSuffix(text, `\`) && isHexValueLine(text) && i+1 < len(lines) {
+			i++
+			raw = append(raw, lines[i])
+			text = strings.TrimSuffix(text, `\`) + strings.TrimSpace(strings.TrimRight(lines[i], "\r"))
+		}
+		value, err := parseValue(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		current.entries = append(current.entries, entry{raw: raw, value: &value})
+	}
+	return f, nil
+}
+
+// Bytes encodes the file. Unmodified content is reproduced exactly.
+func (f *File) Bytes() []byte {
+	var b bytes.Buffer
+	lines := make([]string, 0, len(f.head))
+	lines = append(lines, f.head...)
+	for _, key := range f.keys {
+		lines = append(lines, key.headerLine())
+		for _, e := range key.entries {
+			if e.raw != nil {
+				lines = append(lines, e.raw...)
+				continue
+			}
+			lines = append(lines, encodeValue(*e.value)...)
+		}
+	}
+	for i, line := range lines {
+		b.WriteString(line)
+		if i < len(lines)-1 || !f.noFinalNewline {
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// WriteTo writes the encoded file to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.Bytes())
+	return int64(n), err
+}
+
+// Save writes the file to path atomically via a temporary file in the same
+// directory.
+func (f *File) Save(path string) error {
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp registry file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := f.WriteTo(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("write registry file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close registry file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("chmod registry file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("replace registry file: %w", err)
+	}
+	return nil
+}
+
+// Arch returns the prefix architecture from the #arch= header line
+// ("win32" or "win64"), or "" if the file has none.
+func (f *File) Arch() string {
+	for _, line := range f.head {
+		if value, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "#arch="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// Keys returns all keys in file order.
+func (f *File) Keys() []*Key {
+	return append([]*Key(nil), f.keys...)
+}
+
+// Key returns the key with the given path (case-insensitive), or nil.
+func (f *File) Key(name string) *Key {
+	name = normalizeKeyName(name)
+	for _, key := range f.keys {
+		if strings.EqualFold(key.name, name) {
+			return key
+		}
+	}
+	return nil
+}
+
+// KeysUnder returns the key itself and all keys below it.
+func (f *File) KeysUnder(name string) []*Key {
+	name = strings.ToLower(normalizeKeyName(name))
+	var keys []*Key
+	for _, key := range f.keys {
+		lower := strings.ToLower(key.name)
+		if lower == name || strings.HasPrefix(lower, name+`\`) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// CreateKey returns the key with the given path, appending a new empty key
+// stamped with the current time if it does not exist yet.
+func (f *File) CreateKey(name string) *Key {
+	if key := f.Key(name); key != nil {
+		return key
+	}
+	key := &Key{name: normalizeKeyName(name)}
+	key.Touch(time.Now())
+	f.appendKey(key)
+	return key
+}
+
+// ImportKey copies key (typically from another file) into f. A missing key
+// is copied verbatim including its metadata; an existing key receives all
+// values of key, replacing values with the same name.
+func (f *File) ImportKey(key *Key) *Key {
+	if dst := f.Key(key.name); dst != nil {
+		for _, value := range key.Values() {
+			dst.SetValue(value)
+		}
+		return dst
+	}
+	clone := &Key{name: key.name, modified: key.modified, header: key.header}
+	for _, e := range key.entries {
+		clone.entries = append(clone.entries, e.clone())
+	}
+	f.appendKey(clone)
+	return clone
+}
+
+// DeleteKey removes the key with the given path. It reports whether a key
+// was removed. Subkeys are not affected.
+func (f *File) DeleteKey(name string) bool {
+	name = normalizeKeyName(name)
+	for i, key := range f.keys {
+		if strings.EqualFold(key.name, name) {
+			f.keys = append(f.keys[:i], f.keys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// appendKey adds key at the end, keeping the blank line Wine writes between
+// key sections.
+func (f *File) appendKey(key *Key) {
+	if n := len(f.keys); n > 0 {
+		last := f.keys[n-1]
+		if m := len(last.entries); m == 0 || !last.entries[m-1].isBlank() {
+			last.entries = append(last.entries, entry{raw: []string{""}})
+		}
+	}
+	if m := len(key.entries); m == 0 || !key.entries[m-1].isBlank() {
+		key.entries = append(key.entries, entry{raw: []string{""}})
+	}
+	f.keys = append(f.keys, key)
+}
+
+// Name returns the key path relative to the file root, e.g.
+// `Software\Wine\DllOverrides`.
+func (k *Key) Name() string {
+	return k.name
+}
+
+// Modified returns the key timestamp from the section header.
+func (k *Key) Modified() time.Time {
+	return time.Unix(k.modified, 0)
+}
+
+// Touch sets the key timestamp and the matching #time= metadata line.
+func (k *Key) Touch(t time.Time) {
+	k.modified = t.Unix()
+	k.header = ""
+	timeLine := "#time=" + strconv.FormatInt(toFileTime(t), 16)
+	for i, e := range k.entries {
+		if e.value == nil && len(e.raw) == 1 && strings.HasPrefix(e.raw[0], "#time=") {
+			k.entries[i].raw = []string{timeLine}
+			return
+		}
+	}
+	k.entries = append([]entry{{raw: []string{timeLine}}}, k.entries...)
+}
+
+// Value returns the value with the given name (case-insensitive). The
+// default value has the empty name.
+func (k *Key) Value(name string) (Value, bool) {
+	for _, e := range k.entries {
+		if e.value != nil && strings.EqualFold(e.value.Name, name) {
+			return e.value.clone(), true
+		}
+	}
+	return Value{}, false
+}
+
+// Values returns all values in file order.
+func (k *Key) Values() []Value {
+	values := make([]Value, 0, len(k.entries))
+	for _, e := range k.entries {
+		if e.value != nil {
+			values = append(values, e.value.clone())
+		}
+	}
+	return values
+}
+
+// SetValue replaces the value with the same name in place or inserts it
+// after the last value of the key.
+func (k *Key) SetValue(value Value) {
+	value = value.clone()
+	insertAt := 0
+	for i, e := range k.entries {
+		if e.value != nil && strings.EqualFold(e.value.Name, value.Name) {
+			k.entries[i] = entry{value: &value}
+			return
+		}
+		if e.value != nil || !e.isBlank() {
+			insertAt = i + 1
+		}
+	}
+	k.entries = append(k.entries, entry{})
+	copy(k.entries[insertAt+1:], k.entries[insertAt:])
+	k.entries[insertAt] = entry{value: &value}
+}
+
+// DeleteValue removes the value with the given name and reports whether it
+// existed.
+func (k *Key) DeleteValue(name string) bool {
+	for i, e := range k.entries {
+		if e.value != nil && strings.EqualFold(e.value.Name, name) {
+			k.entries = append(k.entries[:i], k.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Key) headerLine() string {
+	if k.header != "" {
+		return k.header
+	}
+	return "[" + escapeString(k.name, ']') + "] " + strconv.FormatInt(k.modified, 10)
+}
+
+func (e entry) isBlank() bool {
+	return e.value == nil && len(e.raw) == 1 && strings.TrimSpace(e.raw[0]) == ""
+}
+
+func (e entry) clone() entry {
+	c := entry{raw: append([]string(nil), e.raw...)}
+	if e.raw == nil {
+		c.raw = nil
+	}
+	if e.value != nil {
+		v := e.value.clone()
+		c.value = &v
+	}
+	return c
+}
+
+// parseKeyHeader parses "[Name] 1700000000".
+func parseKeyHeader(line string) (string, int64, error) {
+	name, rest, err := unescapeString(line[1:], ']')
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid key header: %w", err)
+	}
+	var modified int64
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		modified, err = strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid key timestamp %q", fields[0])
+		}
+	}
+	return name, modified, nil
+}
+
+func normalizeKeyName(name string) string {
+	return strings.Trim(strings.ReplaceAll(name, "/", `\`), `\`)
+}
+
+// isHexValueLine reports whether a value line uses hex data, the only form
+// Wine wraps over multiple lines.
+func isHexValueLine(line string) bool {
+	var rest string
+	if strings.HasPrefix(line, "@=") {
+		rest = line[2:]
+	} else {
+		_, after, err := unescapeString(line[1:], '"')
+		if err != nil || !strings.HasPrefix(after, "=") {
+			return false
+		}
+		rest = after[1:]
+	}
+	return strings.HasPrefix(rest, "hex:") || strings.HasPrefix(rest, "hex(")
+}
+
+// toFileTime converts t to a Windows FILETIME (100ns intervals since 1601).
+func toFileTime(t time.Time) int64 {
+	return t.UnixNano()/100 + 116444736000000000
+}
@@ -0,0 +1,204 @@
+package winereg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleSystemReg = `WINE REGISTRY Version 2
+;; All keys relative to \\Machine
+
+#arch=win64
+
+[Software\\Microsoft\\NET Framework Setup\\NDP\\v4\\Full] 1700000000
+#time=1da1f3c2b4e5a10
+"Install"=dword:00000001
+"Release"=dword:00080ff8
+"TargetVersion"="4.0.0"
+"Version"="4.8.03761"
+
+[Software\\Microsoft\\Windows NT\\CurrentVersion\\Fonts] 1700000001
+#time=1da1f3c2b4e5a11
+"Arial (TrueType)"="arial.ttf"
+"Path"="C:\\windows\\Fonts\\x\"y\"\t\x00e9z"
+@="default"
+
+[Software\\Wine\\Binary] 1700000002
+#class="Wine"
+"Blob"=hex:00,01,02,03,04,05,06,07,08,09,0a,0b,0c,0d,0e,0f,10,11,12,13,14,15,16,\
+  17,18,19,1a
+"Expand"=str(2):"%SystemRoot%\\system32"
+"Multi"=str(7):"one\0two\0"
+"Legacy"=hex(2):25,00,41,00,00,00
+`
+
+func TestParse_LosslessRoundTrip(t *testing.T) {
+	f, err := Parse([]byte(sampleSystemReg))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(f.Bytes()); got != sampleSystemReg {
+		t.Fatalf("round trip changed content:\n%s", got)
+	}
+	if f.Arch() != "win64" {
+		t.Fatalf("unexpected arch: %q", f.Arch())
+	}
+	if len(f.Keys()) != 3 {
+		t.Fatalf("unexpected key count: %d", len(f.Keys()))
+	}
+}
+
+func TestParse_ValueTypes(t *testing.T) {
+	f, err := Parse([]byte(sampleSystemReg))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ndp := f.Key(`Software\Microsoft\NET Framework Setup\NDP\v4\Full`)
+	if ndp == nil {
+		t.Fatal("expected NDP key")
+	}
+	if ndp.Modified().Unix() != 1700000000 {
+		t.Fatalf("unexpected timestamp: %v", ndp.Modified())
+	}
+	release, ok := ndp.Value("release")
+	if !ok {
+		t.Fatal("expected case-insensitive Release lookup")
+	}
+	if n, ok := release.Uint32(); !ok || n != 528376 {
+		t.Fatalf("unexpected release value: %d (%t)", n, ok)
+	}
+
+	fonts := f.Key(`Software\Microsoft\Windows NT\CurrentVersion\Fonts`)
+	path, _ := fonts.Value("Path")
+	if text, ok := path.Text(); !ok || text != "C:\\windows\\Fonts\\x\"y\"\t\u00e9z" {
+		t.Fatalf("unexpected unescaped string: %q", text)
+	}
+	def, ok := fonts.Value("")
+	if text, _ := def.Text(); !ok || text != "default" {
+		t.Fatalf("unexpected default value: %q", text)
+	}
+
+	binaryKey := f.Key(`Software\Wine\Binary`)
+	blob, _ := binaryKey.Value("Blob")
+	if blob.Type != TypeBinary || len(blob.Data) != 27 || blob.Data[26] != 0x1a {
+		t.Fatalf("unexpected continued hex data: %v", blob.Data)
+	}
+	expand, _ := binaryKey.Value("Expand")
+	if text, ok := expand.Text(); !ok || expand.Type != TypeExpand || text != `%SystemRoot%\system32` {
+		t.Fatalf("unexpected expand string: %q", text)
+	}
+	multi, _ := binaryKey.Value("Multi")
+	if items, ok := multi.Strings(); !ok || len(items) != 2 || items[1] != "two" {
+		t.Fatalf("unexpected multi string: %q", items)
+	}
+	legacy, _ := binaryKey.Value("Legacy")
+	if text, ok := legacy.Text(); !ok || text != "%A" {
+		t.Fatalf("unexpected hex(2) string: %q", text)
+	}
+}
+
+func TestSetValue_OnlyTouchesModifiedLines(t *testing.T) {
+	f, err := Parse([]byte(sampleSystemReg))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fonts := f.Key(`Software\Microsoft\Windows NT\CurrentVersion\Fonts`)
+	fonts.SetValue(StringValue("Arial (TrueType)", "arial2.ttf"))
+	fonts.SetValue(StringValue("Verdana (TrueType)", "verdana.ttf"))
+
+	want := strings.Replace(sampleSystemReg, `"Arial (TrueType)"="arial.ttf"`, `"Arial (TrueType)"="arial2.ttf"`, 1)
+	want = strings.Replace(want, "@=\"default\"\n", "@=\"default\"\n\"Verdana (TrueType)\"=\"verdana.ttf\"\n", 1)
+	if got := string(f.Bytes()); got != want {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
+func TestEncodeValue_MatchesWineLayout(t *testing.T) {
+	cases := []struct {
+		value Value
+		want  string
+	}{
+		{StringValue("mscoree", "native"), `"mscoree"="native"`},
+		{StringValue("", `C:\a"b`), `@="C:\\a\"b"`},
+		{StringValue("name", "\u00e9a\x01"), `"name"="\x00e9a\1"`},
+		{DWordValue("Release", 528040), `"Release"=dword:00080ea8`},
+		{ExpandStringValue("Path", "%WINDIR%"), `"Path"=str(2):"%WINDIR%"`},
+		{MultiStringValue("List", []string{"a", "b"}), `"List"=str(7):"a\0b\0"`},
+		{BinaryValue("Bin", []byte{0xde, 0xad}), `"Bin"=hex:de,ad`},
+		{Value{Name: "Q", Type: TypeQWord, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}}, `"Q"=hex(b):01,00,00,00,00,00,00,00`},
+	}
+	for _, tc := range cases {
+		lines := encodeValue(tc.value)
+		if got := strings.Join(lines, "\n"); got != tc.want {
+			t.Errorf("encode %q: got %s, want %s", tc.value.Name, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeValue_WrapsLongHexData(t *testing.T) {
+	data := make([]byte, 64)
+	lines := encodeValue(BinaryValue("Blob", data))
+	if len(lines) < 2 {
+		t.Fatalf("expected wrapped output, got %q", lines)
+	}
+	f, err := Parse([]byte(Header + "\n\n[K] 1\n" + strings.Join(lines, "\n") + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	blob, _ := f.Key("K").Value("Blob")
+	if len(blob.Data) != 64 {
+		t.Fatalf("unexpected decoded length: %d", len(blob.Data))
+	}
+}
+
+func TestCreateImportAndDeleteKeys(t *testing.T) {
+	src, err := Parse([]byte(sampleSystemReg))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dst, err := Parse([]byte(Header + "\n\n#arch=win64\n\n[Software\\\\Wine] 1\n\"Version\"=\"win10\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, key := range src.KeysUnder(`Software\Microsoft\NET Framework Setup`) {
+		dst.ImportKey(key)
+	}
+	overrides := dst.CreateKey(`Software\Wine\DllOverrides`)
+	overrides.Touch(time.Unix(1700000100, 0))
+	overrides.SetValue(StringValue("mscoree", "native"))
+
+	reparsed, err := Parse(dst.Bytes())
+	if err != nil {
+		t.Fatalf("reparse failed: %v\n%s", err, dst.Bytes())
+	}
+	release, ok := reparsed.Key(`Software\Microsoft\NET Framework Setup\NDP\v4\Full`).Value("Release")
+	if n, _ := release.Uint32(); !ok || n != 528376 {
+		t.Fatalf("imported key missing release value")
+	}
+	if !strings.Contains(string(dst.Bytes()), "\n\n[Software\\\\Wine\\\\DllOverrides] 1700000100\n#time=") {
+		t.Fatalf("unexpected new key layout:\n%s", dst.Bytes())
+	}
+
+	if !reparsed.DeleteKey(`Software\Wine\DllOverrides`) || reparsed.Key(`Software\Wine\DllOverrides`) != nil {
+		t.Fatal("expected key to be deleted")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"missing header":  "[Software] 1\n",
+		"bad timestamp":   Header + "\n[Software] abc\n",
+		"bad dword":       Header + "\n[Software] 1\n\"x\"=dword:zz\n",
+		"unterminated":    Header + "\n[Software] 1\n\"x\"=\"abc\n",
+		"unknown payload": Header + "\n[Software] 1\n\"x\"=foo\n",
+	}
+	for name, content := range cases {
+		if _, err := Parse([]byte(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}